@@ -0,0 +1,288 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Fantom-foundation/go-opera/logger"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+const (
+	// Prefix of the REST endpoints.
+	Prefix = "/api/"
+
+	// DefaultLimit of the ancestors and descendants lists.
+	DefaultLimit = 100
+)
+
+// Server serves REST API over the stored DAG:
+//
+//	GET /api/checkpoint                      - the last stored block;
+//	GET /api/events/{id}                     - event with its parents, children and first-seen times;
+//	GET /api/events/{id}/ancestors?limit=N   - event ancestors;
+//	GET /api/events/{id}/descendants?limit=N - event descendants;
+//	GET /api/blocks/{n}                      - events confirmed by the block;
+//	GET /api/blocks/{n}/snapshot             - DAG frontier and validator heads when the block is decided;
+//	GET /api/epochs/{n}/events               - all the epoch events;
+//	GET /api/epochs/{n}/validators           - per validator stats of the epoch;
+//	GET /api/epochs/{n}/export               - epoch boundaries, validators and events (as export command).
+//
+// Event {id} is either "epoch:lamport:hex" or "0x" prefixed hex.
+type Server struct {
+	storage internal.Storage
+	mux     *http.ServeMux
+
+	logger.Instance
+}
+
+// New server backed by the storage.
+func New(s internal.Storage) *Server {
+	srv := &Server{
+		storage:  s,
+		mux:      http.NewServeMux(),
+		Instance: logger.New("api"),
+	}
+
+	srv.mux.HandleFunc(Prefix+"checkpoint", srv.checkpoint)
+	srv.mux.HandleFunc(Prefix+"events/", srv.events)
+	srv.mux.HandleFunc(Prefix+"blocks/", srv.blocks)
+	srv.mux.HandleFunc(Prefix+"epochs/", srv.epochs)
+
+	return srv
+}
+
+// Handle registers additional handler for the pattern.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.fail(w, http.StatusMethodNotAllowed, "method %s is not allowed", r.Method)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) checkpoint(w http.ResponseWriter, r *http.Request) {
+	block, err := s.storage.GetLastBlock()
+	if err != nil {
+		s.storageFail(w, err)
+		return
+	}
+	s.reply(w, &Checkpoint{
+		Block: block,
+	})
+}
+
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	path := pathArgs(r, "events/")
+	if len(path) < 1 || len(path) > 2 {
+		s.fail(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+		return
+	}
+
+	id, err := internal.ParseEventID(path[0])
+	if err != nil {
+		s.fail(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	if len(path) == 2 {
+		limit, err := queryLimit(r)
+		if err != nil {
+			s.fail(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		var relatives hash.Events
+		switch path[1] {
+		case "ancestors":
+			relatives, err = s.storage.FindAncestors(id, limit)
+		case "descendants":
+			relatives, err = s.storage.FindDescendants(id, limit)
+		default:
+			s.fail(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+			return
+		}
+		if err != nil {
+			s.storageFail(w, err)
+			return
+		}
+		s.reply(w, eventIDs(relatives))
+		return
+	}
+
+	info, err := s.storage.GetEvent(id)
+	if err != nil {
+		s.storageFail(w, err)
+		return
+	}
+	if info == nil {
+		s.fail(w, http.StatusNotFound, "event %s not found", id.FullID())
+		return
+	}
+	children, err := s.storage.GetChildren(id)
+	if err != nil {
+		s.storageFail(w, err)
+		return
+	}
+	event := NewEvent(info)
+	event.Children = eventIDs(children)
+	if ss, ok := s.storage.(internal.SightingsStorage); ok {
+		seen, err := ss.GetSeen(id)
+		if err != nil {
+			s.storageFail(w, err)
+			return
+		}
+		event.Seen = newSightings(seen)
+	}
+	s.reply(w, event)
+}
+
+func (s *Server) blocks(w http.ResponseWriter, r *http.Request) {
+	path := pathArgs(r, "blocks/")
+	if len(path) < 1 || len(path) > 2 || (len(path) == 2 && path[1] != "snapshot") {
+		s.fail(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+		return
+	}
+
+	n, err := strconv.ParseUint(path[0], 10, 64)
+	if err != nil {
+		s.fail(w, http.StatusBadRequest, "invalid block number: %s", path[0])
+		return
+	}
+
+	if len(path) == 2 {
+		snapshot, err := s.storage.GetSnapshot(idx.Block(n))
+		if err != nil {
+			s.storageFail(w, err)
+			return
+		}
+		if snapshot == nil {
+			s.fail(w, http.StatusNotFound, "block %d is not found", n)
+			return
+		}
+		s.reply(w, NewSnapshot(snapshot))
+		return
+	}
+
+	infos, err := s.storage.GetBlockEvents(idx.Block(n))
+	if err != nil {
+		s.storageFail(w, err)
+		return
+	}
+	events := make([]*Event, len(infos))
+	for i, info := range infos {
+		events[i] = NewEvent(info)
+	}
+	s.reply(w, events)
+}
+
+func (s *Server) epochs(w http.ResponseWriter, r *http.Request) {
+	path := pathArgs(r, "epochs/")
+	if len(path) != 2 {
+		s.fail(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+		return
+	}
+
+	n, err := strconv.ParseUint(path[0], 10, 32)
+	if err != nil {
+		s.fail(w, http.StatusBadRequest, "invalid epoch number: %s", path[0])
+		return
+	}
+
+	switch path[1] {
+	case "events":
+		infos, err := s.storage.GetEpochEvents(idx.Epoch(n))
+		if err != nil {
+			s.storageFail(w, err)
+			return
+		}
+		events := make([]*Event, len(infos))
+		for i, info := range infos {
+			events[i] = NewEvent(info)
+		}
+		s.reply(w, events)
+	case "validators":
+		stats, err := s.storage.GetEpochStats(idx.Epoch(n))
+		if err != nil {
+			s.storageFail(w, err)
+			return
+		}
+		res := make([]*ValidatorStats, len(stats))
+		for i, st := range stats {
+			res[i] = newValidatorStats(st)
+		}
+		s.reply(w, res)
+	case "export":
+		infos, err := s.storage.GetEpochEvents(idx.Epoch(n))
+		if err != nil {
+			s.storageFail(w, err)
+			return
+		}
+		var info *internal.EpochInfo
+		if store, ok := s.storage.(internal.EpochStore); ok {
+			info, err = store.GetEpochInfo(idx.Epoch(n))
+			if err != nil {
+				s.storageFail(w, err)
+				return
+			}
+		}
+		s.reply(w, NewEpoch(idx.Epoch(n), info, infos))
+	default:
+		s.fail(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+	}
+}
+
+func (s *Server) reply(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		s.Log.Warn("reply", "err", err)
+	}
+}
+
+func (s *Server) fail(w http.ResponseWriter, status int, format string, a ...interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(map[string]string{
+		"error": fmt.Sprintf(format, a...),
+	})
+	if err != nil {
+		s.Log.Warn("reply", "err", err)
+	}
+}
+
+// storageFail replies with the storage error, which details are logged only.
+func (s *Server) storageFail(w http.ResponseWriter, err error) {
+	s.Log.Error("storage", "err", err)
+	s.fail(w, http.StatusServiceUnavailable, "storage is unavailable")
+}
+
+func pathArgs(r *http.Request, prefix string) []string {
+	path := strings.TrimPrefix(r.URL.Path, Prefix+prefix)
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func queryLimit(r *http.Request) (int, error) {
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.ParseUint(s, 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("invalid limit: %s", s)
+	}
+	return int(limit), nil
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/inter"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+type fakeStorage struct {
+	events map[hash.Event]*internal.EventInfo
+	seen   map[hash.Event][]*internal.Sighting
+	// err is returned by every method if set
+	err error
+}
+
+func (s *fakeStorage) GetLastBlock() (idx.Block, error) {
+	return 7, s.err
+}
+
+func (s *fakeStorage) HasEvent(e hash.Event) (bool, error) {
+	_, ok := s.events[e]
+	return ok, s.err
+}
+
+func (s *fakeStorage) GetEvent(e hash.Event) (*internal.EventInfo, error) {
+	return s.events[e], s.err
+}
+
+func (s *fakeStorage) GetChildren(e hash.Event) (children hash.Events, err error) {
+	for id, info := range s.events {
+		for _, p := range info.Event.Parents() {
+			if p == e {
+				children = append(children, id)
+			}
+		}
+	}
+	return children, s.err
+}
+
+func (s *fakeStorage) GetBlockEvents(n idx.Block) (events []*internal.EventInfo, err error) {
+	for _, info := range s.events {
+		if info.Block == n {
+			events = append(events, info)
+		}
+	}
+	return events, s.err
+}
+
+func (s *fakeStorage) GetEpochEvents(epoch idx.Epoch) (events []*internal.EventInfo, err error) {
+	for id, info := range s.events {
+		if id.Epoch() == epoch {
+			events = append(events, info)
+		}
+	}
+	return events, s.err
+}
+
+func (s *fakeStorage) GetPlaceholders() ([]*internal.EventInfo, error) {
+	return nil, s.err
+}
+
+func (s *fakeStorage) FindAncestors(e hash.Event, limit int) (hash.Events, error) {
+	return s.events[e].Event.Parents(), s.err
+}
+
+func (s *fakeStorage) FindDescendants(e hash.Event, limit int) (hash.Events, error) {
+	return s.GetChildren(e)
+}
+
+func (s *fakeStorage) GetEpochStats(epoch idx.Epoch) ([]*internal.ValidatorStats, error) {
+	return []*internal.ValidatorStats{
+		{Creator: 1, Events: 2},
+	}, s.err
+}
+
+func (s *fakeStorage) GetSeen(e hash.Event) ([]*internal.Sighting, error) {
+	return s.seen[e], s.err
+}
+
+func (s *fakeStorage) GetSnapshot(n idx.Block) (*internal.Snapshot, error) {
+	var atropos *internal.EventInfo
+	var known []*internal.EventInfo
+	for _, info := range s.events {
+		if info.Block == n && info.Role == "atropos" {
+			atropos = info
+		}
+		if info.Block != internal.UnconfirmedBlock && info.Block <= n {
+			known = append(known, info)
+		}
+	}
+	if atropos == nil {
+		return nil, s.err
+	}
+	return internal.NewSnapshot(n, atropos, known), s.err
+}
+
+func TestServer(t *testing.T) {
+	require := require.New(t)
+
+	e1 := &inter.MutableEventPayload{}
+	e1.SetEpoch(2)
+	e1.SetLamport(1)
+	e1.SetCreator(1)
+	parent := &e1.Build().Event
+
+	e2 := &inter.MutableEventPayload{}
+	e2.SetEpoch(2)
+	e2.SetLamport(2)
+	e2.SetCreator(1)
+	e2.SetParents(hash.Events{parent.ID()})
+	child := &e2.Build().Event
+
+	storage := &fakeStorage{
+		events: map[hash.Event]*internal.EventInfo{
+			parent.ID(): {Block: 3, Event: parent},
+			child.ID():  {Block: 3, Event: child, Role: "atropos"},
+		},
+		seen: map[hash.Event][]*internal.Sighting{
+			parent.ID(): {{Node: "ws://node1:4500", At: time.Unix(1, 0).UTC()}},
+		},
+	}
+	srv := New(storage)
+
+	get := func(path string, status int, res interface{}) {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(status, w.Code, path)
+		if res != nil {
+			require.NoError(json.Unmarshal(w.Body.Bytes(), res), path)
+		}
+	}
+
+	var checkpoint Checkpoint
+	get("/api/checkpoint", http.StatusOK, &checkpoint)
+	require.Equal(idx.Block(7), checkpoint.Block)
+
+	var event Event
+	get("/api/events/"+parent.ID().FullID(), http.StatusOK, &event)
+	require.Equal(parent.ID().FullID(), event.ID)
+	require.Equal([]string{child.ID().FullID()}, event.Children)
+	require.Equal([]*Sighting{{Node: "ws://node1:4500", At: time.Unix(1, 0).UTC()}}, event.Seen)
+
+	event = Event{}
+	get("/api/events/"+child.ID().Hex(), http.StatusOK, &event)
+	require.Equal("atropos", event.Role)
+	require.Equal([]string{parent.ID().FullID()}, event.Parents)
+	require.Empty(event.Seen)
+
+	var ids []string
+	get("/api/events/"+child.ID().FullID()+"/ancestors?limit=10", http.StatusOK, &ids)
+	require.Equal([]string{parent.ID().FullID()}, ids)
+
+	var events []*Event
+	get("/api/blocks/3", http.StatusOK, &events)
+	require.Len(events, 2)
+
+	var snapshot Snapshot
+	get("/api/blocks/3/snapshot", http.StatusOK, &snapshot)
+	require.Equal(child.ID().FullID(), snapshot.Atropos)
+	require.Equal(2, snapshot.Events)
+	require.Equal([]string{child.ID().FullID()}, snapshot.Frontier)
+	require.Len(snapshot.Heads, 1)
+	require.Equal(child.ID().FullID(), snapshot.Heads[0].ID)
+	get("/api/blocks/2/snapshot", http.StatusNotFound, nil)
+	get("/api/blocks/3/unknown", http.StatusNotFound, nil)
+
+	events = nil
+	get("/api/epochs/2/events", http.StatusOK, &events)
+	require.Len(events, 2)
+
+	var stats []*ValidatorStats
+	get("/api/epochs/2/validators", http.StatusOK, &stats)
+	require.Len(stats, 1)
+
+	var epoch Epoch
+	get("/api/epochs/2/export", http.StatusOK, &epoch)
+	require.Equal(idx.Epoch(2), epoch.Epoch)
+	require.Zero(epoch.FirstBlock)
+	require.Len(epoch.Events, 2)
+	require.Equal(parent.ID().FullID(), epoch.Events[0].ID)
+
+	get("/api/events/"+hash.FakeEvent().FullID(), http.StatusNotFound, nil)
+	get("/api/events/wrong", http.StatusBadRequest, nil)
+	get("/api/events/1:2:zz", http.StatusBadRequest, nil)
+	get("/api/events/1:2:"+strings.Repeat("00", 23), http.StatusBadRequest, nil)
+	get("/api/events/0x"+strings.Repeat("00", 31), http.StatusBadRequest, nil)
+	get("/api/events/0x"+strings.Repeat("zz", 32), http.StatusBadRequest, nil)
+	get("/api/blocks/x", http.StatusBadRequest, nil)
+	get("/api/epochs/2/unknown", http.StatusNotFound, nil)
+
+	storage.err = errors.New("connection refused")
+	get("/api/checkpoint", http.StatusServiceUnavailable, nil)
+	get("/api/events/"+parent.ID().FullID(), http.StatusServiceUnavailable, nil)
+	get("/api/epochs/2/validators", http.StatusServiceUnavailable, nil)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// Event is a JSON view of the stored event.
+type Event struct {
+	ID        string          `json:"id"`
+	Epoch     idx.Epoch       `json:"epoch"`
+	Lamport   idx.Lamport     `json:"lamport"`
+	Creator   idx.ValidatorID `json:"creator"`
+	Block     idx.Block       `json:"block"`
+	Role      string          `json:"role"`
+	Parents   []string        `json:"parents"`
+	Children  []string        `json:"children,omitempty"`
+	Integrity string          `json:"integrity,omitempty"`
+	Seen      []*Sighting     `json:"seen,omitempty"`
+}
+
+// Sighting is a JSON view of the time when the node serves the event first.
+type Sighting struct {
+	Node string    `json:"node"`
+	At   time.Time `json:"at"`
+}
+
+// Checkpoint is a JSON view of the last stored block.
+type Checkpoint struct {
+	Block idx.Block `json:"block"`
+}
+
+// ValidatorStats is a JSON view of the per-epoch validator statistics.
+type ValidatorStats struct {
+	Creator      idx.ValidatorID `json:"creator"`
+	Events       int             `json:"events"`
+	Atropoi      int             `json:"atropoi"`
+	FirstLamport idx.Lamport     `json:"firstLamport"`
+	LastLamport  idx.Lamport     `json:"lastLamport"`
+}
+
+// Snapshot is a JSON view of the DAG when the block is decided.
+type Snapshot struct {
+	Block   idx.Block `json:"block"`
+	Epoch   idx.Epoch `json:"epoch"`
+	Atropos string    `json:"atropos"`
+	// Events is a count of the epoch events known as of the atropos
+	Events int `json:"events"`
+	// Frontier is the known events which are not parents of the other known ones
+	Frontier []string `json:"frontier"`
+	// Heads is the latest known event of each validator
+	Heads []*Event `json:"heads"`
+}
+
+// Epoch is a JSON view of the epoch with its boundaries, validators and events,
+// which is a self-contained unit of the epoch DAG.
+type Epoch struct {
+	Epoch idx.Epoch `json:"epoch"`
+	// FirstBlock and SealingBlock are 0 if the epoch info is not recorded,
+	// SealingBlock is 0 also if the epoch is not sealed yet
+	FirstBlock   idx.Block `json:"firstBlock"`
+	SealingBlock idx.Block `json:"sealingBlock"`
+	// Tail is true if the events which are not confirmed by any block are recorded also
+	Tail       bool         `json:"tail"`
+	Validators []*Validator `json:"validators"`
+	// Events are ordered by lamport, so parents go first
+	Events []*Event `json:"events"`
+}
+
+// Validator is a JSON view of the epoch validator.
+type Validator struct {
+	ID     idx.ValidatorID `json:"id"`
+	Weight string          `json:"weight"`
+	PubKey string          `json:"pubkey"`
+}
+
+// NewEvent makes JSON view of the event.
+func NewEvent(info *internal.EventInfo) *Event {
+	id := info.Event.ID()
+	return &Event{
+		ID:      id.FullID(),
+		Epoch:   id.Epoch(),
+		Lamport: id.Lamport(),
+		Creator: info.Event.Creator(),
+		Block:   info.Block,
+		Role:    info.Role,
+		Parents: eventIDs(info.Event.Parents()),
+
+		Integrity: info.Integrity,
+	}
+}
+
+func newSightings(seen []*internal.Sighting) []*Sighting {
+	res := make([]*Sighting, len(seen))
+	for i, s := range seen {
+		res[i] = &Sighting{
+			Node: s.Node,
+			At:   s.At,
+		}
+	}
+	return res
+}
+
+func newValidatorStats(st *internal.ValidatorStats) *ValidatorStats {
+	return &ValidatorStats{
+		Creator:      st.Creator,
+		Events:       st.Events,
+		Atropoi:      st.Atropoi,
+		FirstLamport: st.FirstLamport,
+		LastLamport:  st.LastLamport,
+	}
+}
+
+func eventIDs(ids hash.Events) []string {
+	res := make([]string, len(ids))
+	for i, id := range ids {
+		res[i] = id.FullID()
+	}
+	return res
+}
+
+// NewEpoch makes JSON view of the epoch, info is nil if it is not recorded.
+func NewEpoch(epoch idx.Epoch, info *internal.EpochInfo, events []*internal.EventInfo) *Epoch {
+	res := &Epoch{
+		Epoch:      epoch,
+		Validators: []*Validator{},
+		Events:     make([]*Event, len(events)),
+	}
+	if info != nil {
+		res.FirstBlock = info.FirstBlock
+		res.SealingBlock = info.SealingBlock
+		res.Tail = info.Tail
+		for _, v := range info.Validators {
+			res.Validators = append(res.Validators, &Validator{
+				ID:     v.ID,
+				Weight: v.Weight.String(),
+				PubKey: v.PubKey,
+			})
+		}
+	}
+
+	ordered := append([]*internal.EventInfo{}, events...)
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i].Event.ID(), ordered[j].Event.ID()
+		if a.Lamport() != b.Lamport() {
+			return a.Lamport() < b.Lamport()
+		}
+		return bytes.Compare(a.Bytes(), b.Bytes()) < 0
+	})
+	for i, e := range ordered {
+		res.Events[i] = NewEvent(e)
+	}
+	return res
+}
+
+// NewSnapshot makes JSON view of the snapshot.
+func NewSnapshot(s *internal.Snapshot) *Snapshot {
+	res := &Snapshot{
+		Block:    s.Block,
+		Epoch:    s.Atropos.Event.Epoch(),
+		Atropos:  s.Atropos.Event.ID().FullID(),
+		Events:   s.Events,
+		Frontier: make([]string, len(s.Frontier)),
+		Heads:    make([]*Event, len(s.Heads)),
+	}
+	for i, info := range s.Frontier {
+		res.Frontier[i] = info.Event.ID().FullID()
+	}
+	for i, info := range s.Heads {
+		res.Heads[i] = NewEvent(info)
+	}
+	return res
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+)
+
+var (
+	nodesFlag = cli.StringSliceFlag{
+		Name:  "node",
+		Usage: "opera API url of the compared node (repeat for each node)",
+	}
+
+	pollFlag = cli.DurationFlag{
+		Name:  "poll",
+		Usage: "interval to poll the nodes DAG heads",
+		Value: 500 * time.Millisecond,
+	}
+
+	durationFlag = cli.DurationFlag{
+		Name:  "duration",
+		Usage: "time to compare the nodes, 0 to compare until interrupted",
+		Value: 0,
+	}
+
+	cmdCompare = cli.Command{
+		Name: "compare",
+		Flags: []cli.Flag{
+			neo4jUrlFlag,
+			nodesFlag,
+			pollFlag,
+			durationFlag,
+		},
+		Action: cmd(actCompare),
+		Usage:  "Compare the events propagation between several nodes and write first-seen times into db.",
+	}
+)
+
+func actCompare(ctx context.Context, cli *cli.Context) error {
+	urls := cli.StringSlice(nodesFlag.Name)
+	if len(urls) < 2 {
+		return fmt.Errorf("at least 2 nodes are required, use --%s", nodesFlag.Name)
+	}
+
+	disk := cli.String(neo4jUrlFlag.Name)
+	log.Info("open DB", "path", neo4j.Redacted(disk))
+	db, err := neo4j.New(disk)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var (
+		sightings = make(chan sighting, 100)
+		nodes     = make([]*NodeWatcher, len(urls))
+		poll      = cli.Duration(pollFlag.Name)
+	)
+	for i, url := range urls {
+		nodes[i] = NewNodeWatcher(url, i, poll, sightings)
+	}
+	comparison := NewComparison(nodes, db)
+
+	var stop <-chan time.Time
+	if d := cli.Duration(durationFlag.Name); d > 0 {
+		stop = time.After(d)
+	}
+
+compare:
+	for {
+		select {
+		case s := <-sightings:
+			err = comparison.Add(s)
+			if err != nil {
+				log.Error("Stop comparing, sightings are not stored", "err", err)
+				break compare
+			}
+		case <-stop:
+			break compare
+		case <-ctx.Done():
+			break compare
+		}
+	}
+
+	for _, n := range nodes {
+		n.Close()
+	}
+	for len(sightings) > 0 && err == nil {
+		err = comparison.Add(<-sightings)
+	}
+
+	WriteReport(os.Stdout, comparison.Report())
+	return err
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+)
+
+var (
+	// errDiverged exits with code 2 (see main), the other errors (of db) exit with 1
+	errDiverged = cli.NewExitError("captures diverge", 2)
+
+	cmdDiff = cli.Command{
+		Name:      "diff",
+		ArgsUsage: "<neo4j url A> <neo4j url B>",
+		Action:    cmd(actDiff),
+		Usage:     "Compare two DAG captures, exit with code 2 if they diverge.",
+	}
+)
+
+func actDiff(ctx context.Context, cli *cli.Context) error {
+	if cli.NArg() != 2 {
+		return errors.New("two db urls are required")
+	}
+
+	var stores [2]*neo4j.Db
+	for i, disk := range cli.Args() {
+		log.Info("open DB", "path", neo4j.Redacted(disk))
+		db, err := neo4j.Open(disk)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		stores[i] = db
+	}
+
+	diffs, err := DiffCaptures(stores[0], stores[1])
+	if err != nil {
+		return err
+	}
+	WriteDiff(os.Stdout, diffs)
+	if len(diffs) > 0 {
+		log.Warn("Captures diverge", "epochs", len(diffs))
+		return errDiverged
+	}
+
+	log.Info("Captures are identical")
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/api"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/reader"
+)
+
+var (
+	epochsFlag = cli.StringFlag{
+		Name:  "epochs",
+		Usage: "epochs range a..b (or a single epoch a)",
+	}
+
+	incompleteFlag = cli.BoolFlag{
+		Name:  "incomplete",
+		Usage: "export the sealed epochs whose tail events are not read also, they have \"tail\": false",
+	}
+
+	outFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "file to write, stdout by default",
+	}
+
+	cmdExport = cli.Command{
+		Name: "export",
+		Flags: []cli.Flag{
+			neo4jUrlFlag,
+			epochsFlag,
+			incompleteFlag,
+			outFlag,
+		},
+		Action: cmd(actExport),
+		Usage: "Write the epochs with their boundaries, validators and events, one JSON per line. " +
+			"It fails on a sealed epoch whose events not confirmed by any block are not read, unless --incomplete.",
+	}
+)
+
+// epochsFrom parses --epochs, zero range if it is not set.
+func epochsFrom(cli *cli.Context) (reader.EpochRange, error) {
+	s := cli.String(epochsFlag.Name)
+	if s == "" {
+		return reader.EpochRange{}, nil
+	}
+	return reader.ParseEpochRange(s)
+}
+
+func actExport(ctx context.Context, cli *cli.Context) error {
+	epochs, err := epochsFrom(cli)
+	if err != nil {
+		return err
+	}
+	if epochs.IsZero() {
+		return errors.New("--epochs is required")
+	}
+
+	disk := cli.String(neo4jUrlFlag.Name)
+	log.Info("open DB", "path", neo4j.Redacted(disk))
+	db, err := neo4j.Open(disk)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	if path := cli.String(outFlag.Name); path != "" {
+		log.Info("open output file", "path", path)
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	incomplete := cli.Bool(incompleteFlag.Name)
+	enc := json.NewEncoder(w)
+	for epoch := epochs.From; epoch <= epochs.To && ctx.Err() == nil; epoch++ {
+		info, err := db.GetEpochInfo(epoch)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			log.Warn("Epoch info is not recorded, read the epoch by saveto --epochs", "epoch", epoch)
+		} else if info.SealingBlock != 0 && !info.Tail {
+			if !incomplete {
+				return fmt.Errorf("epoch %d is incomplete, its tail events were not read before it was sealed "+
+					"(use --%s to export it anyway)", epoch, incompleteFlag.Name)
+			}
+			log.Warn("Epoch tail events are not read", "epoch", epoch)
+		}
+		events, err := db.GetEpochEvents(epoch)
+		if err != nil {
+			return err
+		}
+
+		err = enc.Encode(api.NewEpoch(epoch, info, events))
+		if err != nil {
+			return err
+		}
+		log.Info("Exported", "epoch", epoch, "events", len(events))
+	}
+	return nil
+}
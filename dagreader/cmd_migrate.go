@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+)
+
+var (
+	dryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "list the pending migrations without applying them",
+	}
+
+	cmdMigrate = cli.Command{
+		Name: "migrate",
+		Flags: []cli.Flag{
+			neo4jUrlFlag,
+			dryRunFlag,
+		},
+		Action: cmd(actMigrate),
+		Usage:  "Upgrade db schema to the latest version.",
+	}
+)
+
+func actMigrate(ctx context.Context, cli *cli.Context) error {
+	disk := cli.String(neo4jUrlFlag.Name)
+	log.Info("open DB", "path", neo4j.Redacted(disk))
+	db, err := neo4j.Open(disk)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	log.Info("Db schema", "version", version, "latest", neo4j.LatestSchemaVersion())
+
+	pending, err := db.PendingMigrations()
+	if err != nil {
+		return err
+	}
+	for _, m := range pending {
+		fmt.Printf("%d\t%s\n", m.Version, m.Description)
+		for _, step := range m.Steps() {
+			fmt.Printf("\t- %s\n", step)
+		}
+	}
+	if len(pending) < 1 {
+		log.Info("Db schema is up to date")
+		return nil
+	}
+	if cli.Bool(dryRunFlag.Name) {
+		log.Info("Dry run, db is not changed", "pending", len(pending))
+		return nil
+	}
+
+	err = db.Migrate()
+	if err != nil {
+		return err
+	}
+	log.Info("Db schema is upgraded", "version", neo4j.LatestSchemaVersion())
+	return nil
+}
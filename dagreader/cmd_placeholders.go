@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+)
+
+var (
+	cmdPlaceholders = cli.Command{
+		Name: "placeholders",
+		Flags: []cli.Flag{
+			neo4jUrlFlag,
+		},
+		Action: cmd(actPlaceholders),
+		Usage:  "List events in db which are detected but still not found.",
+	}
+)
+
+func actPlaceholders(ctx context.Context, cli *cli.Context) error {
+	disk := cli.String(neo4jUrlFlag.Name)
+	log.Info("open DB", "path", neo4j.Redacted(disk))
+	db, err := neo4j.Open(disk)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	placeholders, err := db.GetPlaceholders()
+	if err != nil {
+		return err
+	}
+	for _, p := range placeholders {
+		fmt.Printf("%s\tblock=%d\trole=%s\n", p.Event.ID().FullID(), p.Block, p.Role)
+	}
+	log.Info("Unresolved placeholders", "count", len(placeholders))
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+)
+
+var (
+	retainEpochsFlag = cli.Uint64Flag{
+		Name:  "retain.epochs",
+		Usage: "number of the last epochs to keep in db, 0 to keep all",
+	}
+
+	retainBlocksFlag = cli.Uint64Flag{
+		Name:  "retain.blocks",
+		Usage: "number of the last blocks to keep in db, 0 to keep all",
+	}
+
+	pruneIntervalFlag = cli.DurationFlag{
+		Name:  "prune.interval",
+		Usage: "interval to prune db out of retention (see --retain.epochs and --retain.blocks) while saving, 0 to disable",
+		Value: time.Hour,
+	}
+
+	cmdPrune = cli.Command{
+		Name: "prune",
+		Flags: []cli.Flag{
+			neo4jUrlFlag,
+			retainEpochsFlag,
+			retainBlocksFlag,
+		},
+		Action: cmd(actPrune),
+		Usage:  "Delete events out of retention from db.",
+	}
+)
+
+func retentionFrom(cli *cli.Context) neo4j.Retention {
+	return neo4j.Retention{
+		Epochs: idx.Epoch(cli.Uint64(retainEpochsFlag.Name)),
+		Blocks: idx.Block(cli.Uint64(retainBlocksFlag.Name)),
+	}
+}
+
+func actPrune(ctx context.Context, cli *cli.Context) error {
+	retention := retentionFrom(cli)
+	if !retention.Enabled() {
+		log.Warn("No retention, nothing to prune (see --retain.epochs and --retain.blocks)")
+		return nil
+	}
+
+	disk := cli.String(neo4jUrlFlag.Name)
+	log.Info("open DB", "path", neo4j.Redacted(disk))
+	db, err := neo4j.New(disk)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	res, err := db.Prune(ctx, retention)
+	if err != nil {
+		return err
+	}
+	log.Info("Pruned", "epoch", res.Epoch, "block", res.Block, "events", res.Events, "sightings", res.Sightings)
+	return nil
+}
+
+// pruneLoop prunes db periodically until ctx is done.
+func pruneLoop(ctx context.Context, db *neo4j.Db, retention neo4j.Retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			res, err := db.Prune(ctx, retention)
+			if err != nil {
+				log.Error("Prune failed", "err", err)
+				continue
+			}
+			if res.Events > 0 {
+				log.Info("Pruned", "epoch", res.Epoch, "block", res.Block, "events", res.Events, "sightings", res.Sightings)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/Fantom-foundation/lachesis-base/inter/idx"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/urfave/cli"
 
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/ndjson"
 	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/reader"
 )
 
 var (
@@ -17,10 +23,95 @@ var (
 		Value: neo4j.DefaultDb,
 	}
 
+	placeholdersRetryFlag = cli.DurationFlag{
+		Name:  "placeholders.retry",
+		Usage: "interval to retry getting of not found events, 0 to disable",
+		Value: 10 * time.Minute,
+	}
+
+	headsFlag = cli.DurationFlag{
+		Name:  "heads",
+		Usage: "interval to poll DAG heads to capture events which are not confirmed by blocks, 0 to disable",
+		Value: 0,
+	}
+
+	datadirFlag = cli.StringFlag{
+		Name:  "datadir",
+		Usage: "datadir of a stopped opera node to read DAG from instead of API",
+	}
+
+	ndjsonFlag = cli.StringFlag{
+		Name:  "ndjson",
+		Usage: "file to append events to as newline delimited JSON, in addition to db",
+	}
+
+	recordFlag = cli.StringFlag{
+		Name:  "record",
+		Usage: "file to record every request and response of the reader to",
+	}
+
+	replayFlag = cli.StringFlag{
+		Name:  "replay",
+		Usage: "recorded file to read DAG from instead of API",
+	}
+
+	replaySpeedFlag = cli.Float64Flag{
+		Name:  "replay.speed",
+		Usage: "replay speed factor relative to the recorded timing, 0 for no delays",
+		Value: 1,
+	}
+
+	bufferEventsFlag = cli.IntFlag{
+		Name:  "buffer.events",
+		Usage: "max number of the events which wait for their parents, the oldest are dropped above it",
+		Value: int(reader.DefaultBufferConfig().Limit.Num),
+	}
+
+	bufferSizeFlag = cli.IntFlag{
+		Name:  "buffer.size",
+		Usage: "max size (MiB) of the events which wait for their parents, the oldest are dropped above it",
+		Value: int(reader.DefaultBufferConfig().Limit.Size / opt.MiB),
+	}
+
+	bufferTimeoutFlag = cli.DurationFlag{
+		Name:  "buffer.timeout",
+		Usage: "time to wait for missing parents before re-request them and then fill the gaps with markers, 0 to wait forever",
+		Value: reader.DefaultBufferConfig().GapTimeout,
+	}
+
+	concurrencyFlag = cli.IntFlag{
+		Name:  "concurrency",
+		Usage: "number of parallel event requests",
+		Value: reader.DefaultConfig().Concurrency,
+	}
+
 	cmdSaveTo = cli.Command{
 		Name: "saveto",
 		Flags: []cli.Flag{
 			neo4jUrlFlag,
+			placeholdersRetryFlag,
+			headsFlag,
+			datadirFlag,
+			ndjsonFlag,
+			recordFlag,
+			replayFlag,
+			replaySpeedFlag,
+			bufferEventsFlag,
+			bufferSizeFlag,
+			bufferTimeoutFlag,
+			concurrencyFlag,
+			epochsFlag,
+			retainEpochsFlag,
+			retainBlocksFlag,
+			pruneIntervalFlag,
+			verifyFlag,
+			verifyReportFlag,
+			alertsSilentFlag,
+			alertsLagFlag,
+			alertsUnreferencedFlag,
+			alertsValidatorFlag,
+			alertsFileFlag,
+			alertsWebhookFlag,
 		},
 		Action: cmd(actSaveTo),
 		Usage:  "Write DAG into db.",
@@ -28,32 +119,136 @@ var (
 )
 
 func actSaveTo(ctx context.Context, cli *cli.Context) error {
+	verify := cli.Bool(verifyFlag.Name)
+	if verify && (cli.String(recordFlag.Name) != "" || cli.String(replayFlag.Name) != "") {
+		return errors.New("--verify is not supported with --record and --replay, they keep no payloads")
+	}
+	liveness, err := livenessFrom(cli)
+	if err != nil {
+		return err
+	}
+	epochs, err := epochsFrom(cli)
+	if err != nil {
+		return err
+	}
+
 	disk := cli.String(neo4jUrlFlag.Name)
-	log.Info("open DB", "path", disk)
+	log.Info("open DB", "path", neo4j.Redacted(disk))
 	db, err := neo4j.New(disk)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	buffer := NewEventsBuffer(db, ctx.Done())
-	defer buffer.Close()
+	if retention, interval := retentionFrom(cli), cli.Duration(pruneIntervalFlag.Name); retention.Enabled() && interval > 0 {
+		pruneCtx, stop := context.WithCancel(ctx)
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			pruneLoop(pruneCtx, db, retention, interval)
+		}()
+		// before db is closed
+		defer func() {
+			stop()
+			<-stopped
+		}()
+	}
 
-	rpc := cli.GlobalString(operaApiUrlFlag.Name)
-	dagStart := idx.Block(cli.GlobalUint64(dagStartFlag.Name))
+	var src reader.Source
+	if path := cli.String(replayFlag.Name); path != "" {
+		log.Info("replay session", "path", path)
+		src = reader.ReplaySource(path, cli.Float64(replaySpeedFlag.Name))
+	} else if datadir := cli.String(datadirFlag.Name); datadir != "" {
+		log.Info("open datadir", "path", datadir)
+		src = reader.DatadirSource(datadir)
+	} else {
+		rpc := cli.GlobalString(operaApiUrlFlag.Name)
+		log.Info("connect to API", "url", rpc)
+		src = reader.RPCSource(rpc)
+	}
 
-	log.Info("connect to API", "url", rpc)
-	reader := NewReader(rpc, dagStart, db)
-	defer reader.Close()
+	var (
+		sinks        []reader.Sink
+		onValidators func(idx.Epoch, []*reader.EpochValidator)
+	)
+	if path := cli.String(ndjsonFlag.Name); path != "" {
+		log.Info("open NDJSON file", "path", path)
+		file, err := ndjson.New(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		sinks = append(sinks, file)
+	}
+	if metrics.Enabled {
+		sinks = append(sinks, metricsSink{})
+	}
+	if path := cli.String(verifyReportFlag.Name); verify && path != "" {
+		log.Info("open verification report", "path", path)
+		report, err := newVerifyReport(path)
+		if err != nil {
+			return err
+		}
+		defer report.Close()
+		sinks = append(sinks, report)
+	}
+	if liveness.Enabled() {
+		var senders []AlertSender
+		if path := cli.String(alertsFileFlag.Name); path != "" {
+			log.Info("open alerts file", "path", path)
+			file, err := newAlertsFile(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			senders = append(senders, file)
+		}
+		if url := cli.String(alertsWebhookFlag.Name); url != "" {
+			log.Info("send alerts to webhook", "url", url)
+			hook := newAlertsWebhook(url)
+			defer hook.Close()
+			senders = append(senders, hook)
+		}
+		sink := NewLivenessSink(liveness, senders...)
+		onValidators = sink.SetValidators
+		sinks = append(sinks, sink)
+	}
 
-	for {
-		select {
-		case e := <-reader.Events():
-			buffer.Push(e)
-		case <-ctx.Done():
-			return nil
+	if path := cli.String(recordFlag.Name); path != "" {
+		log.Info("record session", "path", path)
+		recorder, err := reader.NewRecorder(path)
+		if err != nil {
+			return err
 		}
+		defer func() {
+			if err := recorder.Close(); err != nil {
+				log.Error("Session record is incomplete", "path", path, "err", err)
+			}
+		}()
+		src = recorder.Wrap(src)
 	}
 
+	cfg := reader.DefaultConfig()
+	cfg.Source = src
+	cfg.DagStart = idx.Block(cli.GlobalUint64(dagStartFlag.Name))
+	cfg.Db = db
+	cfg.RetryInterval = cli.Duration(placeholdersRetryFlag.Name)
+	cfg.HeadsInterval = cli.Duration(headsFlag.Name)
+	cfg.Concurrency = cli.Int(concurrencyFlag.Name)
+	cfg.Buffer.Limit.Num = idx.Event(cli.Int(bufferEventsFlag.Name))
+	cfg.Buffer.Limit.Size = uint64(cli.Int(bufferSizeFlag.Name)) * opt.MiB
+	cfg.Buffer.GapTimeout = cli.Duration(bufferTimeoutFlag.Name)
+	cfg.Verify = verify
+	cfg.Epochs = epochs
+	cfg.OnValidators = onValidators
+
+	res, err := reader.Run(ctx, cfg, sinks...)
+	if err != nil {
+		if res != nil {
+			log.Error("stopped on failure", "dropped", res.Dropped, "resume", res.Resume)
+		}
+		return err
+	}
+	log.Info("stopped", "dropped", res.Dropped, "resume", res.Resume)
 	return nil
 }
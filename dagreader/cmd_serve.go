@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/api"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/web"
+)
+
+var (
+	listenFlag = cli.StringFlag{
+		Name:  "listen",
+		Usage: "HTTP API listen address",
+		Value: "127.0.0.1:8080",
+	}
+
+	cmdServe = cli.Command{
+		Name: "serve",
+		Flags: []cli.Flag{
+			neo4jUrlFlag,
+			listenFlag,
+		},
+		Action: cmd(actServe),
+		Usage:  "Serve HTTP API and web visualizer over the DAG in db.",
+	}
+)
+
+func actServe(ctx context.Context, cli *cli.Context) error {
+	disk := cli.String(neo4jUrlFlag.Name)
+	log.Info("open DB", "path", neo4j.Redacted(disk))
+	db, err := neo4j.Open(disk)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	handler := api.New(db)
+	handler.Handle("/", web.Handler())
+
+	srv := &http.Server{
+		Addr:    cli.String(listenFlag.Name),
+		Handler: handler,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Info("serve HTTP API and visualizer", "url", "http://"+srv.Addr+"/")
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdown)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/api"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+)
+
+var (
+	blockFlag = cli.Uint64Flag{
+		Name:  "block",
+		Usage: "block number",
+	}
+
+	formatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "output format: json (as API), ndjson (API event per line) or text",
+		Value: "json",
+	}
+
+	cmdSnapshot = cli.Command{
+		Name: "snapshot",
+		Flags: []cli.Flag{
+			neo4jUrlFlag,
+			blockFlag,
+			formatFlag,
+		},
+		Action: cmd(actSnapshot),
+		Usage:  "Print DAG frontier and per validator heads when the block is decided.",
+	}
+)
+
+func actSnapshot(ctx context.Context, cli *cli.Context) error {
+	if !cli.IsSet(blockFlag.Name) {
+		return errors.New("--block is required")
+	}
+	block := idx.Block(cli.Uint64(blockFlag.Name))
+	format := cli.String(formatFlag.Name)
+	switch format {
+	case "json", "ndjson", "text":
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	disk := cli.String(neo4jUrlFlag.Name)
+	log.Info("open DB", "path", neo4j.Redacted(disk))
+	db, err := neo4j.Open(disk)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snapshot, err := db.GetSnapshot(block)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("atropos of block %d is not found", block)
+	}
+
+	err = WriteSnapshot(os.Stdout, snapshot, format)
+	if err != nil {
+		return err
+	}
+	log.Info("Snapshot", "block", block, "atropos", snapshot.Atropos.Event.ID(), "events", snapshot.Events,
+		"frontier", len(snapshot.Frontier), "validators", len(snapshot.Heads))
+	return nil
+}
+
+// WriteSnapshot writes the snapshot in the format.
+func WriteSnapshot(w io.Writer, s *internal.Snapshot, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(api.NewSnapshot(s))
+	}
+
+	// the frontier events are mostly the heads also
+	var (
+		events   = append([]*internal.EventInfo{}, s.Heads...)
+		frontier = make(map[*internal.EventInfo]bool, len(s.Frontier))
+		heads    = make(map[*internal.EventInfo]bool, len(s.Heads))
+	)
+	for _, info := range s.Heads {
+		heads[info] = true
+	}
+	for _, info := range s.Frontier {
+		frontier[info] = true
+		if !heads[info] {
+			events = append(events, info)
+		}
+	}
+
+	switch format {
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, info := range events {
+			if err := enc.Encode(api.NewEvent(info)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "text":
+		for _, info := range events {
+			_, err := fmt.Fprintf(w, "%s\tcreator=%d\tblock=%d\thead=%t\tfrontier=%t\n",
+				info.Event.ID().FullID(), info.Event.Creator(), info.Block, heads[info], frontier[info])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/Fantom-foundation/go-opera/ftmclient"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/neo4j"
+)
+
+var (
+	traceDepthFlag = cli.Uint64Flag{
+		Name:  "depth",
+		Usage: "lamport depth of the atropos ancestors to search for the carrier event if the block events are not stored in db",
+		Value: 30,
+	}
+
+	cmdTraceTx = cli.Command{
+		Name:      "trace-tx",
+		ArgsUsage: "<tx hash>",
+		Flags: []cli.Flag{
+			neo4jUrlFlag,
+			traceDepthFlag,
+		},
+		Action: cmd(actTraceTx),
+		Usage:  "Print timeline of the transaction from the event which carries it to the block which executes it.",
+	}
+)
+
+func actTraceTx(ctx context.Context, cli *cli.Context) error {
+	if cli.NArg() != 1 {
+		return errors.New("tx hash is required")
+	}
+	tx := common.HexToHash(cli.Args().First())
+
+	url := cli.GlobalString(operaApiUrlFlag.Name)
+	log.Info("connect to API", "url", url)
+	client, err := ftmclient.Dial(url)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var db internal.Storage
+	disk := cli.String(neo4jUrlFlag.Name)
+	log.Info("open DB", "path", neo4j.Redacted(disk))
+	if stored, err := neo4j.Open(disk); err != nil {
+		log.Warn("No db, block events are fetched", "err", err)
+	} else {
+		defer stored.Close()
+		db = stored
+	}
+
+	trace, err := TraceTx(ctx, client, db, tx, idx.Lamport(cli.Uint64(traceDepthFlag.Name)))
+	if err != nil {
+		return err
+	}
+	log.Info("Traced", "tx", tx, "block", trace.Block, "searched", trace.Searched, "stored", trace.Stored)
+	return WriteTimeline(os.Stdout, trace)
+}
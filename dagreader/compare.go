@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/logger"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/reader"
+)
+
+// sighting is the first time the node serves the event.
+type sighting struct {
+	node int
+	id   hash.Event
+	at   time.Time
+}
+
+// NodeWatcher detects the new events served by one of the compared nodes.
+type NodeWatcher struct {
+	url    string
+	num    int
+	dial   reader.Dialer
+	output chan<- sighting
+	poll   time.Duration
+	done   chan struct{}
+	work   sync.WaitGroup
+
+	seen map[hash.Event]struct{}
+	// baseline is the max lamport of the DAG heads at start,
+	// events of the epoch up to it (and of the older epochs) are not compared
+	baseline struct {
+		epoch   idx.Epoch
+		lamport idx.Lamport
+	}
+
+	logger.Instance
+}
+
+func NewNodeWatcher(url string, num int, poll time.Duration, output chan<- sighting) *NodeWatcher {
+	w := &NodeWatcher{
+		url:      url,
+		num:      num,
+		dial:     reader.DialRPC,
+		output:   output,
+		poll:     poll,
+		done:     make(chan struct{}),
+		seen:     make(map[hash.Event]struct{}),
+		Instance: logger.New(fmt.Sprintf("node%d", num)),
+	}
+
+	w.work.Add(1)
+	go w.background()
+
+	return w
+}
+
+func (w *NodeWatcher) Close() {
+	select {
+	case <-w.done:
+		return
+	default:
+		close(w.done)
+	}
+	w.work.Wait()
+}
+
+// IsOld returns true if the event was in the node DAG before the comparison started.
+func (w *NodeWatcher) IsOld(e hash.Event) bool {
+	if w.baseline.epoch == 0 {
+		return true
+	}
+	if e.Epoch() != w.baseline.epoch {
+		return e.Epoch() < w.baseline.epoch
+	}
+	return e.Lamport() <= w.baseline.lamport
+}
+
+func (w *NodeWatcher) background() {
+	defer w.work.Done()
+	w.Log.Info("starting", "url", w.url)
+	defer w.Log.Info("stopped")
+
+	var (
+		client  reader.Client
+		sbscr   ethereum.Subscription
+		headers = make(chan *types.Header, 1)
+		err     error
+	)
+
+	disconnect := func() {
+		if sbscr != nil {
+			sbscr.Unsubscribe()
+			sbscr = nil
+		}
+		if client != nil {
+			client.Close()
+			client = nil
+		}
+	}
+	defer disconnect()
+
+	ticker := time.NewTicker(w.poll)
+	defer ticker.Stop()
+
+	for {
+		for client == nil {
+			select {
+			case <-w.done:
+				return
+			default:
+			}
+			client, err = w.dial(w.url)
+			if err != nil {
+				rpcErrorsCounter("Dial").Inc(1)
+				w.Log.Error("connect to", "url", w.url, "err", err)
+				delay()
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			sbscr, err = client.SubscribeNewHead(ctx, headers)
+			cancel()
+			if err != nil {
+				rpcErrorsCounter("SubscribeNewHead").Inc(1)
+				w.Log.Error("subscribe to new blocks", "err", err)
+				disconnect()
+				delay()
+				continue
+			}
+		}
+
+		select {
+		case <-ticker.C:
+			err = w.readHeads(client)
+		case b := <-headers:
+			err = w.readBlock(client, b.Number)
+		case err = <-sbscr.Err():
+		case <-w.done:
+			return
+		}
+		if err != nil {
+			disconnect()
+			delay()
+		}
+	}
+}
+
+// readHeads detects the new events by the current epoch DAG heads.
+func (w *NodeWatcher) readHeads(client reader.Client) error {
+	at := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	// -1 is for the current epoch, the node serves no heads of the others
+	heads, err := client.GetHeads(ctx, big.NewInt(-1))
+	cancel()
+	if err != nil {
+		rpcErrorsCounter("GetHeads").Inc(1)
+		w.Log.Error("get heads", "err", err)
+		return err
+	}
+
+	if w.baseline.epoch == 0 && len(heads) > 0 {
+		w.baseline.epoch = heads[0].Epoch()
+		for _, h := range heads {
+			if w.baseline.lamport < h.Lamport() {
+				w.baseline.lamport = h.Lamport()
+			}
+		}
+		w.Log.Info("baseline", "epoch", w.baseline.epoch, "lamport", w.baseline.lamport)
+		return nil
+	}
+
+	for _, h := range heads {
+		err = w.walk(client, h, at)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBlock detects the new events by the block atropos.
+func (w *NodeWatcher) readBlock(client reader.Client, n *big.Int) error {
+	at := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	blk, err := client.BlockByNumber(ctx, n)
+	cancel()
+	if err != nil {
+		rpcErrorsCounter("BlockByNumber").Inc(1)
+		w.Log.Error("get block", "n", n, "err", err)
+		return err
+	}
+
+	return w.walk(client, hash.Event(blk.Hash()), at)
+}
+
+// walk gets the root event and its unseen ancestors and reports them as seen at the time.
+func (w *NodeWatcher) walk(client reader.Client, root hash.Event, at time.Time) error {
+	queue := []hash.Event{root}
+	for len(queue) > 0 {
+		e := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		if _, seen := w.seen[e]; seen || w.IsOld(e) {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+		event, err := client.GetEvent(ctx, e)
+		cancel()
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				continue
+			}
+			rpcErrorsCounter("GetEvent").Inc(1)
+			w.Log.Error("get event", "id", e, "err", err)
+			return err
+		}
+		eventsFetchedCounter().Inc(1)
+
+		w.seen[e] = struct{}{}
+		select {
+		case w.output <- sighting{node: w.num, id: e, at: at}:
+		case <-w.done:
+			return fmt.Errorf("interrupted")
+		}
+
+		queue = append(queue, event.Parents()...)
+	}
+
+	return nil
+}
+
+// Comparison collects the events first-seen times of the nodes.
+type Comparison struct {
+	nodes []*NodeWatcher
+	seen  map[hash.Event][]time.Time
+	store internal.Sightings
+}
+
+func NewComparison(nodes []*NodeWatcher, store internal.Sightings) *Comparison {
+	return &Comparison{
+		nodes: nodes,
+		seen:  make(map[hash.Event][]time.Time),
+		store: store,
+	}
+}
+
+// Add the sighting, error is of the sightings store.
+func (c *Comparison) Add(s sighting) error {
+	times := c.seen[s.id]
+	if times == nil {
+		times = make([]time.Time, len(c.nodes))
+		c.seen[s.id] = times
+	}
+	if !times[s.node].IsZero() {
+		return nil
+	}
+	times[s.node] = s.at
+	if c.store != nil {
+		return c.store.SetSeen(c.nodes[s.node].url, s.id, s.at)
+	}
+	return nil
+}
+
+// NodeReport is the propagation summary of the node.
+type NodeReport struct {
+	Url     string
+	Seen    int
+	Delays  []time.Duration
+	Missing hash.Events
+}
+
+// Percentile returns the propagation delay percentile.
+func (r *NodeReport) Percentile(p int) time.Duration {
+	if len(r.Delays) < 1 {
+		return 0
+	}
+	return r.Delays[(len(r.Delays)-1)*p/100]
+}
+
+// Report calculates the nodes delays relative to the first node served the event,
+// and the events the node has never served. The nodes should be stopped already.
+func (c *Comparison) Report() []*NodeReport {
+	reports := make([]*NodeReport, len(c.nodes))
+	for i, n := range c.nodes {
+		reports[i] = &NodeReport{
+			Url: n.url,
+		}
+	}
+
+	candidates := make([]hash.Events, len(c.nodes))
+	for e, times := range c.seen {
+		old := false
+		for _, n := range c.nodes {
+			// never connected node has no baseline
+			old = old || (n.baseline.epoch != 0 && n.IsOld(e))
+		}
+		if old {
+			continue
+		}
+
+		var first time.Time
+		for _, t := range times {
+			if !t.IsZero() && (first.IsZero() || t.Before(first)) {
+				first = t
+			}
+		}
+		for i, t := range times {
+			if t.IsZero() {
+				candidates[i] = append(candidates[i], e)
+				continue
+			}
+			reports[i].Seen++
+			reports[i].Delays = append(reports[i].Delays, t.Sub(first))
+		}
+	}
+
+	for i, n := range c.nodes {
+		sort.Slice(reports[i].Delays, func(a, b int) bool {
+			return reports[i].Delays[a] < reports[i].Delays[b]
+		})
+		reports[i].Missing = n.notServed(candidates[i])
+		hash.OrderedEvents(reports[i].Missing).ByEpochAndLamport()
+	}
+
+	return reports
+}
+
+// notServed checks the events the node was not seen with, whether it serves them now.
+func (w *NodeWatcher) notServed(ee hash.Events) hash.Events {
+	if len(ee) < 1 {
+		return nil
+	}
+	client, err := w.dial(w.url)
+	if err != nil {
+		w.Log.Warn("missing events are not verified", "err", err)
+		return ee
+	}
+	defer client.Close()
+
+	var missing hash.Events
+	for _, e := range ee {
+		ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+		_, err := client.GetEvent(ctx, e)
+		cancel()
+		if err != nil {
+			missing = append(missing, e)
+		}
+	}
+	return missing
+}
+
+// WriteReport prints the nodes reports.
+func WriteReport(out io.Writer, reports []*NodeReport) {
+	for _, r := range reports {
+		fmt.Fprintf(out, "%s\tseen=%d\tmissing=%d\tp50=%s\tp90=%s\tp99=%s\tmax=%s\n",
+			r.Url, r.Seen, len(r.Missing),
+			r.Percentile(50), r.Percentile(90), r.Percentile(99), r.Percentile(100))
+	}
+	for _, r := range reports {
+		for _, e := range r.Missing {
+			fmt.Fprintf(out, "%s\tmissing\t%s\n", r.Url, e.FullID())
+		}
+	}
+}
+
+func delay() {
+	<-time.After(2 * time.Second)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/logger"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag/tdag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/reader"
+)
+
+func TestComparisonReport(t *testing.T) {
+	require := require.New(t)
+
+	nodes := make([]*NodeWatcher, 2)
+	for i := range nodes {
+		nodes[i] = &NodeWatcher{
+			url: []string{"a", "b"}[i],
+			num: i,
+
+			Instance: logger.New("test"),
+			dial: func(string) (reader.Client, error) {
+				return nil, errors.New("offline")
+			},
+		}
+		nodes[i].baseline.epoch = 2
+		nodes[i].baseline.lamport = 10
+	}
+	c := NewComparison(nodes, nil)
+
+	var (
+		start = time.Now()
+		old   = fakeEventID(2, 10)
+		e1    = fakeEventID(2, 11)
+		e2    = fakeEventID(2, 12)
+	)
+	require.NoError(c.Add(sighting{node: 0, id: old, at: start}))
+	require.NoError(c.Add(sighting{node: 0, id: e1, at: start}))
+	require.NoError(c.Add(sighting{node: 1, id: e1, at: start.Add(time.Second)}))
+	require.NoError(c.Add(sighting{node: 1, id: e1, at: start.Add(time.Minute)}))
+	require.NoError(c.Add(sighting{node: 1, id: e2, at: start}))
+
+	reports := c.Report()
+	require.Len(reports, 2)
+
+	require.Equal(1, reports[0].Seen)
+	require.Equal(time.Duration(0), reports[0].Percentile(100))
+	require.Equal(hash.Events{e2}, reports[0].Missing)
+
+	require.Equal(2, reports[1].Seen)
+	require.Equal(time.Second, reports[1].Percentile(100))
+	require.Empty(reports[1].Missing)
+}
+
+// operaNode serves the epoch events and, like go-opera, the heads of the current epoch only.
+type operaNode struct {
+	epoch  idx.Epoch
+	events map[hash.Event]dag.Event
+}
+
+func (n *operaNode) add(lamport idx.Lamport, parents ...hash.Event) hash.Event {
+	e := &tdag.TestEvent{}
+	e.SetEpoch(n.epoch)
+	e.SetLamport(lamport)
+	e.SetParents(parents)
+	e.SetID([24]byte{byte(lamport)})
+	n.events[e.ID()] = e
+	return e.ID()
+}
+
+func (n *operaNode) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return nil, ethereum.NotFound
+}
+
+func (n *operaNode) GetEvent(ctx context.Context, h hash.Event) (dag.Event, error) {
+	e, ok := n.events[h]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return e, nil
+}
+
+// GetHeads serves -1 ("pending") and the current epoch number, nil is "latest" which is the sealed epoch.
+func (n *operaNode) GetHeads(ctx context.Context, epoch *big.Int) (hash.Events, error) {
+	if epoch == nil || epoch.Sign() >= 0 && idx.Epoch(epoch.Uint64()) != n.epoch {
+		return nil, errors.New("heads for previous epochs are not available")
+	}
+	parents := hash.EventsSet{}
+	for _, e := range n.events {
+		parents.Add(e.Parents()...)
+	}
+	var heads hash.Events
+	for id := range n.events {
+		if !parents.Contains(id) {
+			heads = append(heads, id)
+		}
+	}
+	return heads, nil
+}
+
+func (n *operaNode) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	}), nil
+}
+
+func (n *operaNode) Close() {}
+
+func TestNodeWatcherHeads(t *testing.T) {
+	require := require.New(t)
+
+	node := &operaNode{
+		epoch:  5,
+		events: make(map[hash.Event]dag.Event),
+	}
+	e1 := node.add(1)
+	e2 := node.add(2, e1)
+
+	output := make(chan sighting, 10)
+	w := &NodeWatcher{
+		num:      1,
+		output:   output,
+		done:     make(chan struct{}),
+		seen:     make(map[hash.Event]struct{}),
+		Instance: logger.New("test"),
+	}
+
+	// the first heads are the baseline
+	require.NoError(w.readHeads(node))
+	require.Equal(idx.Epoch(5), w.baseline.epoch)
+	require.Equal(idx.Lamport(2), w.baseline.lamport)
+	require.True(w.IsOld(e2))
+	require.Empty(output)
+
+	e3 := node.add(3, e2)
+	e4 := node.add(4, e3)
+	require.NoError(w.readHeads(node))
+	require.Len(output, 2)
+	for _, e := range []hash.Event{e4, e3} {
+		s := <-output
+		require.Equal(1, s.node)
+		require.Equal(e, s.id)
+	}
+}
+
+func fakeEventID(epoch, lamport uint32) hash.Event {
+	return hash.Event(reader.NotFoundEvent(hash.Event{
+		0, 0, 0, byte(epoch), 0, 0, 0, byte(lamport), 0xff,
+	}).ID())
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode"
+
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/naoina/toml"
+	"github.com/urfave/cli"
+)
+
+var configFileFlag = cli.StringFlag{
+	Name:  "config",
+	Usage: "TOML configuration file, flags override it",
+}
+
+// tomlSettings ensure that TOML keys use the same names as Go struct fields.
+var tomlSettings = toml.Config{
+	NormFieldName: func(rt reflect.Type, key string) string {
+		return key
+	},
+	FieldToKey: func(rt reflect.Type, field string) string {
+		return field
+	},
+	MissingField: func(rt reflect.Type, field string) error {
+		link := ""
+		if unicode.IsUpper(rune(rt.Name()[0])) && rt.PkgPath() != "main" {
+			link = fmt.Sprintf(", see https://godoc.org/%s#%s for available fields", rt.PkgPath(), rt.Name())
+		}
+		return fmt.Errorf("field '%s' is not defined in %s%s", field, rt.String(), link)
+	},
+}
+
+// Config is the dagreader configuration file, its defaults are the flag ones.
+type Config struct {
+	// API is the opera node API url
+	API      string
+	DagStart idx.Block
+
+	Neo4j     Neo4jConfig
+	Reader    ReaderConfig
+	Metrics   MetricsConfig
+	Export    ExportConfig
+	Retention RetentionConfig
+}
+
+// Neo4jConfig is the db connection.
+type Neo4jConfig struct {
+	URL      string
+	User     string
+	Password string
+}
+
+// ReaderConfig is saveto reading options.
+type ReaderConfig struct {
+	Concurrency       int
+	PlaceholdersRetry Duration
+	Heads             Duration
+	BufferEvents      int
+	BufferSizeMiB     int
+	BufferTimeout     Duration
+}
+
+// MetricsConfig is the metrics collection and export.
+type MetricsConfig struct {
+	Enabled            bool
+	PrometheusEndpoint string
+}
+
+// ExportConfig is the default outputs.
+type ExportConfig struct {
+	// NDJSON is a file saveto appends events to
+	NDJSON string
+	// SnapshotFormat of snapshot command
+	SnapshotFormat string
+}
+
+// RetentionConfig is the db pruning.
+type RetentionConfig struct {
+	Epochs        idx.Epoch
+	Blocks        idx.Block
+	PruneInterval Duration
+}
+
+// withCredentials adds the user to db url which has no user.
+func (c Neo4jConfig) withCredentials(db string) string {
+	u, err := url.Parse(db)
+	if err != nil || c.User == "" || u.User != nil {
+		return db
+	}
+	u.User = url.UserPassword(c.User, c.Password)
+	return u.String()
+}
+
+// Duration is a TOML string like "10m" or "1h30m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// DefaultConfig returns the flag defaults.
+func DefaultConfig() *Config {
+	return &Config{
+		API:      operaApiUrlFlag.Value,
+		DagStart: idx.Block(dagStartFlag.Value),
+		Neo4j: Neo4jConfig{
+			URL: neo4jUrlFlag.Value,
+		},
+		Reader: ReaderConfig{
+			Concurrency:       concurrencyFlag.Value,
+			PlaceholdersRetry: Duration(placeholdersRetryFlag.Value),
+			Heads:             Duration(headsFlag.Value),
+			BufferEvents:      bufferEventsFlag.Value,
+			BufferSizeMiB:     bufferSizeFlag.Value,
+			BufferTimeout:     Duration(bufferTimeoutFlag.Value),
+		},
+		Metrics: MetricsConfig{
+			PrometheusEndpoint: metricsPrometheusEndpointFlag.Value,
+		},
+		Export: ExportConfig{
+			NDJSON:         ndjsonFlag.Value,
+			SnapshotFormat: formatFlag.Value,
+		},
+		Retention: RetentionConfig{
+			Epochs:        idx.Epoch(retainEpochsFlag.Value),
+			Blocks:        idx.Block(retainBlocksFlag.Value),
+			PruneInterval: Duration(pruneIntervalFlag.Value),
+		},
+	}
+}
+
+// Load reads the file over the config, unknown keys are errors.
+func (cfg *Config) Load(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	err = tomlSettings.Unmarshal(data, cfg)
+	if err != nil {
+		return errors.New(file + ", " + err.Error())
+	}
+
+	return nil
+}
+
+// flags returns the config values of the global and command flags by their names.
+func (cfg *Config) flags() (global, command map[string]string) {
+	global = map[string]string{
+		operaApiUrlFlag.Name:               cfg.API,
+		dagStartFlag.Name:                  strconv.FormatUint(uint64(cfg.DagStart), 10),
+		metricsEnabledFlag.Name:            strconv.FormatBool(cfg.Metrics.Enabled),
+		metricsPrometheusEndpointFlag.Name: cfg.Metrics.PrometheusEndpoint,
+	}
+
+	command = map[string]string{
+		neo4jUrlFlag.Name:          cfg.Neo4j.withCredentials(cfg.Neo4j.URL),
+		concurrencyFlag.Name:       strconv.Itoa(cfg.Reader.Concurrency),
+		placeholdersRetryFlag.Name: time.Duration(cfg.Reader.PlaceholdersRetry).String(),
+		headsFlag.Name:             time.Duration(cfg.Reader.Heads).String(),
+		bufferEventsFlag.Name:      strconv.Itoa(cfg.Reader.BufferEvents),
+		bufferSizeFlag.Name:        strconv.Itoa(cfg.Reader.BufferSizeMiB),
+		bufferTimeoutFlag.Name:     time.Duration(cfg.Reader.BufferTimeout).String(),
+		ndjsonFlag.Name:            cfg.Export.NDJSON,
+		formatFlag.Name:            cfg.Export.SnapshotFormat,
+		retainEpochsFlag.Name:      strconv.FormatUint(uint64(cfg.Retention.Epochs), 10),
+		retainBlocksFlag.Name:      strconv.FormatUint(uint64(cfg.Retention.Blocks), 10),
+		pruneIntervalFlag.Name:     time.Duration(cfg.Retention.PruneInterval).String(),
+	}
+	return
+}
+
+// openConfig reads --config file, nil if it is not set.
+func openConfig(ctx *cli.Context) (*Config, error) {
+	file := ctx.GlobalString(configFileFlag.Name)
+	if file == "" {
+		return nil, nil
+	}
+	cfg := DefaultConfig()
+	return cfg, cfg.Load(file)
+}
+
+// applyGlobalConfig sets the global flags which are not set by command line from --config file.
+func applyGlobalConfig(ctx *cli.Context) error {
+	cfg, err := openConfig(ctx)
+	if cfg == nil || err != nil {
+		return err
+	}
+
+	global, _ := cfg.flags()
+	for _, name := range ctx.GlobalFlagNames() {
+		if value, ok := global[name]; ok && !ctx.GlobalIsSet(name) {
+			if err := ctx.GlobalSet(name, value); err != nil {
+				return fmt.Errorf("config %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyCommandConfig sets the command flags which are not set by command line from --config file.
+func applyCommandConfig(ctx *cli.Context) error {
+	cfg, err := openConfig(ctx)
+	if cfg == nil || err != nil {
+		return err
+	}
+
+	_, command := cfg.flags()
+	// db url of the command line gets credentials of the file
+	if ctx.IsSet(neo4jUrlFlag.Name) {
+		command[neo4jUrlFlag.Name] = cfg.Neo4j.withCredentials(ctx.String(neo4jUrlFlag.Name))
+		if err := ctx.Set(neo4jUrlFlag.Name, command[neo4jUrlFlag.Name]); err != nil {
+			return fmt.Errorf("config %s: %w", neo4jUrlFlag.Name, err)
+		}
+	}
+	for _, name := range ctx.FlagNames() {
+		if value, ok := command[name]; ok && !ctx.IsSet(name) {
+			if err := ctx.Set(name, value); err != nil {
+				return fmt.Errorf("config %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
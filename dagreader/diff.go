@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// DiffSource is a DAG capture to compare.
+type DiffSource interface {
+	GetEpochs() ([]idx.Epoch, error)
+	GetEpochEvents(idx.Epoch) ([]*internal.EventInfo, error)
+	GetAtropoi() (map[idx.Block]hash.Event, error)
+}
+
+// BlockDiff is a block with different atropoi, the atropos is zero if the block is in the other capture only.
+type BlockDiff struct {
+	Block idx.Block
+	A, B  hash.Event
+}
+
+// EpochDiff is a difference of the two captures in the epoch.
+type EpochDiff struct {
+	Epoch    idx.Epoch
+	OnlyA    hash.Events
+	OnlyB    hash.Events
+	Parents  hash.Events
+	Creators hash.Events
+	Atropoi  []BlockDiff
+}
+
+// Empty returns true if there is no difference.
+func (d *EpochDiff) Empty() bool {
+	return len(d.OnlyA)+len(d.OnlyB)+len(d.Parents)+len(d.Creators)+len(d.Atropoi) == 0
+}
+
+// DiffCaptures compares the two captures and returns the diverged epochs.
+// Parents and creator of not found placeholders are unknown so they are not compared.
+func DiffCaptures(a, b DiffSource) ([]*EpochDiff, error) {
+	diffs := make(map[idx.Epoch]*EpochDiff)
+	get := func(epoch idx.Epoch) *EpochDiff {
+		d, ok := diffs[epoch]
+		if !ok {
+			d = &EpochDiff{Epoch: epoch}
+			diffs[epoch] = d
+		}
+		return d
+	}
+
+	epochs := make(map[idx.Epoch]struct{})
+	for _, src := range []DiffSource{a, b} {
+		ee, err := src.GetEpochs()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range ee {
+			epochs[e] = struct{}{}
+		}
+	}
+
+	for epoch := range epochs {
+		eventsA, err := a.GetEpochEvents(epoch)
+		if err != nil {
+			return nil, err
+		}
+		eventsB, err := b.GetEpochEvents(epoch)
+		if err != nil {
+			return nil, err
+		}
+
+		aa := make(map[hash.Event]*internal.EventInfo)
+		for _, info := range eventsA {
+			aa[info.Event.ID()] = info
+		}
+
+		for _, info := range eventsB {
+			id := info.Event.ID()
+			other, ok := aa[id]
+			if !ok {
+				get(epoch).OnlyB = append(get(epoch).OnlyB, id)
+				continue
+			}
+			delete(aa, id)
+
+			if internal.IsPlaceholder(info.Role) || internal.IsPlaceholder(other.Role) {
+				continue
+			}
+			if info.Event.Creator() != other.Event.Creator() {
+				get(epoch).Creators = append(get(epoch).Creators, id)
+			}
+			if !sameEvents(info.Event.Parents(), other.Event.Parents()) {
+				get(epoch).Parents = append(get(epoch).Parents, id)
+			}
+		}
+
+		for id := range aa {
+			get(epoch).OnlyA = append(get(epoch).OnlyA, id)
+		}
+	}
+
+	atropoiA, err := a.GetAtropoi()
+	if err != nil {
+		return nil, err
+	}
+	atropoiB, err := b.GetAtropoi()
+	if err != nil {
+		return nil, err
+	}
+	for n, atropos := range atropoiA {
+		other := atropoiB[n]
+		if other == atropos {
+			continue
+		}
+		d := get(atropos.Epoch())
+		d.Atropoi = append(d.Atropoi, BlockDiff{Block: n, A: atropos, B: other})
+	}
+	for n, atropos := range atropoiB {
+		if _, ok := atropoiA[n]; ok {
+			continue
+		}
+		d := get(atropos.Epoch())
+		d.Atropoi = append(d.Atropoi, BlockDiff{Block: n, B: atropos})
+	}
+
+	list := make([]*EpochDiff, 0, len(diffs))
+	for _, d := range diffs {
+		hash.OrderedEvents(d.OnlyA).ByEpochAndLamport()
+		hash.OrderedEvents(d.OnlyB).ByEpochAndLamport()
+		hash.OrderedEvents(d.Parents).ByEpochAndLamport()
+		hash.OrderedEvents(d.Creators).ByEpochAndLamport()
+		sort.Slice(d.Atropoi, func(i, j int) bool {
+			return d.Atropoi[i].Block < d.Atropoi[j].Block
+		})
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Epoch < list[j].Epoch
+	})
+
+	return list, nil
+}
+
+func sameEvents(a, b hash.Events) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := a.Set()
+	for _, e := range b {
+		if !set.Contains(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteDiff prints the differences grouped by epoch.
+func WriteDiff(out io.Writer, diffs []*EpochDiff) {
+	for _, d := range diffs {
+		fmt.Fprintf(out, "epoch %d\n", d.Epoch)
+		for _, e := range d.OnlyA {
+			fmt.Fprintf(out, "\tonly in A\t%s\n", e.FullID())
+		}
+		for _, e := range d.OnlyB {
+			fmt.Fprintf(out, "\tonly in B\t%s\n", e.FullID())
+		}
+		for _, e := range d.Parents {
+			fmt.Fprintf(out, "\tparents differ\t%s\n", e.FullID())
+		}
+		for _, e := range d.Creators {
+			fmt.Fprintf(out, "\tcreator differs\t%s\n", e.FullID())
+		}
+		for _, b := range d.Atropoi {
+			switch {
+			case b.B == hash.ZeroEvent:
+				fmt.Fprintf(out, "\tblock only in A\tblock=%d\tA=%s\n", b.Block, b.A.FullID())
+			case b.A == hash.ZeroEvent:
+				fmt.Fprintf(out, "\tblock only in B\tblock=%d\tB=%s\n", b.Block, b.B.FullID())
+			default:
+				fmt.Fprintf(out, "\tatropos differs\tblock=%d\tA=%s\tB=%s\n", b.Block, b.A.FullID(), b.B.FullID())
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+type fakeCapture struct {
+	events  []*internal.EventInfo
+	atropoi map[idx.Block]hash.Event
+}
+
+func (c *fakeCapture) GetEpochs() ([]idx.Epoch, error) {
+	return []idx.Epoch{2}, nil
+}
+
+func (c *fakeCapture) GetEpochEvents(epoch idx.Epoch) ([]*internal.EventInfo, error) {
+	return c.events, nil
+}
+
+func (c *fakeCapture) GetAtropoi() (map[idx.Block]hash.Event, error) {
+	return c.atropoi, nil
+}
+
+func TestDiffCaptures(t *testing.T) {
+	require := require.New(t)
+
+	event := func(lamport idx.Lamport, creator idx.ValidatorID, parents ...hash.Event) *internal.EventInfo {
+		e := &dag.MutableBaseEvent{}
+		e.SetEpoch(2)
+		e.SetLamport(lamport)
+		e.SetCreator(creator)
+		e.SetParents(parents)
+		return &internal.EventInfo{
+			Block: 1,
+			Event: e.Build([24]byte{byte(creator)}),
+		}
+	}
+
+	e1 := event(1, 1)
+	e2 := event(1, 2)
+	e3 := event(2, 1, e1.Event.ID())
+	e3x := event(2, 1, e1.Event.ID(), e2.Event.ID())
+	only := event(3, 3)
+
+	a := &fakeCapture{
+		events:  []*internal.EventInfo{e1, e2, e3},
+		atropoi: map[idx.Block]hash.Event{1: e1.Event.ID()},
+	}
+	diffs, err := DiffCaptures(a, a)
+	require.NoError(err)
+	require.Empty(diffs)
+
+	b := &fakeCapture{
+		events:  []*internal.EventInfo{e1, e2, e3x, only},
+		atropoi: map[idx.Block]hash.Event{1: e2.Event.ID(), 2: e3x.Event.ID()},
+	}
+	diffs, err = DiffCaptures(a, b)
+	require.NoError(err)
+	require.Len(diffs, 1)
+	require.Equal(idx.Epoch(2), diffs[0].Epoch)
+	require.Empty(diffs[0].OnlyA)
+	require.Equal(hash.Events{only.Event.ID()}, diffs[0].OnlyB)
+	require.Empty(diffs[0].Creators)
+	require.Len(diffs[0].Parents, 1)
+	require.Equal([]BlockDiff{
+		{Block: 1, A: e1.Event.ID(), B: e2.Event.ID()},
+		{Block: 2, B: e3x.Event.ID()},
+	}, diffs[0].Atropoi)
+
+	diffs, err = DiffCaptures(b, a)
+	require.NoError(err)
+	require.Len(diffs, 1)
+	require.Equal([]BlockDiff{
+		{Block: 1, A: e2.Event.ID(), B: e1.Event.ID()},
+		{Block: 2, A: e3x.Event.ID()},
+	}, diffs[0].Atropoi)
+
+	out := &strings.Builder{}
+	WriteDiff(out, diffs)
+	require.Contains(out.String(), "block only in A\tblock=2")
+}
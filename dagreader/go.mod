@@ -1,12 +1,13 @@
 module github.com/Fantom-foundation/lachesis-dag-tool/dagreader
 
-go 1.14
+go 1.16
 
 require (
 	github.com/Fantom-foundation/go-opera v1.1.1-rc.2
 	github.com/Fantom-foundation/lachesis-base v0.0.0-20220103160934-6b4931c60582
 	github.com/ethereum/go-ethereum v1.10.8
 	github.com/hashicorp/golang-lru v0.5.5-0.20210104140557-80c98217689d
+	github.com/naoina/toml v0.1.2-0.20170918210437-9fafd6967416
 	github.com/neo4j/neo4j-go-driver v1.8.3
 	github.com/paulbellamy/ratecounter v0.2.0
 	github.com/stretchr/testify v1.7.0
@@ -1,27 +1,113 @@
 package internal
 
 import (
+	"strings"
+	"time"
+
 	"github.com/Fantom-foundation/lachesis-base/hash"
 	"github.com/Fantom-foundation/lachesis-base/inter/dag"
 	"github.com/Fantom-foundation/lachesis-base/inter/idx"
 )
 
+const (
+	// PlaceholderMark ends role of the event which is detected but not found.
+	PlaceholderMark = "*"
+
+	// GapRole is a role of the missing event which is put by buffer in place of the never arrived one.
+	GapRole = "gap" + PlaceholderMark
+
+	// UnconfirmedBlock is a block of the events which are not confirmed by any block yet.
+	UnconfirmedBlock idx.Block = 0
+)
+
+// Storage is the stored events and checkpoint.
+// Implementations retry transient errors themselves, so a returned error is fatal.
 type Storage interface {
-	GetLastBlock() idx.Block
-	HasEvent(hash.Event) bool
-	GetEvent(hash.Event) *EventInfo
+	GetLastBlock() (idx.Block, error)
+	HasEvent(hash.Event) (bool, error)
+	GetEvent(hash.Event) (*EventInfo, error)
+	GetChildren(hash.Event) (hash.Events, error)
+	GetBlockEvents(idx.Block) ([]*EventInfo, error)
+	GetEpochEvents(idx.Epoch) ([]*EventInfo, error)
+	FindAncestors(e hash.Event, limit int) (hash.Events, error)
+	FindDescendants(e hash.Event, limit int) (hash.Events, error)
+	GetEpochStats(idx.Epoch) ([]*ValidatorStats, error)
+	GetPlaceholders() ([]*EventInfo, error)
+	// GetSnapshot returns nil if atropos of the block is not stored.
+	GetSnapshot(idx.Block) (*Snapshot, error)
+}
+
+// Sink writes ordered events and calls EventInfo.Done when the event is written.
+// Load returns on the first write error, the rest of events are not written.
+type Sink interface {
+	Load(events <-chan *EventInfo) error
 }
 
 type Db interface {
 	Storage
-	Load(events <-chan *EventInfo)
+	Sink
+	SetLastBlock(idx.Block) error
+}
+
+// Sightings stores the time when the node serves the event first.
+type Sightings interface {
+	SetSeen(node string, e hash.Event, at time.Time) error
+}
+
+// SightingsStorage is a Storage of the sightings.
+type SightingsStorage interface {
+	// GetSeen returns the sightings of the event ordered by time.
+	GetSeen(hash.Event) ([]*Sighting, error)
+}
+
+// Sighting is the time when the node serves the event first.
+type Sighting struct {
+	Node string
+	At   time.Time
+}
+
+// DagStartState caches the detected first block with DAG.
+type DagStartState interface {
+	// GetDagStart returns 0 if the block is not detected yet.
+	GetDagStart() (idx.Block, error)
+	SetDagStart(idx.Block) error
+}
+
+// PruneState remembers the cutoff of the pruned events, so they are not read again.
+type PruneState interface {
+	// GetPruneCutoff returns the zero cutoff if nothing is pruned.
+	GetPruneCutoff() (PruneCutoff, error)
+}
+
+// PruneCutoff bounds the pruned events.
+type PruneCutoff struct {
+	// Epoch is the last pruned epoch, 0 if none
+	Epoch idx.Epoch
+	// BlockEpoch is the epoch of the last pruned block, its events up to
+	// the Lamport of the block atropos are pruned (they are the atropos ancestors)
+	BlockEpoch idx.Epoch
+	Lamport    idx.Lamport
+}
+
+// Pruned returns true if the event is below the cutoff. An event of BlockEpoch which
+// is confirmed later than the pruned block but is below its atropos Lamport is pruned too.
+func (c PruneCutoff) Pruned(e hash.Event) bool {
+	return e.Epoch() <= c.Epoch ||
+		e.Epoch() < c.BlockEpoch ||
+		e.Epoch() == c.BlockEpoch && e.Lamport() <= c.Lamport
 }
 
 type EventInfo struct {
-	Block   idx.Block
-	Event   dag.Event
-	Role    string
-	Dispose func()
+	Block idx.Block
+	Event dag.Event
+	Role  string
+	// Update means the event replaces its stored version
+	// (not found placeholder or unconfirmed event).
+	Update bool
+	// Integrity lists the failed verification checks of the event,
+	// empty if it passes them or is not verified.
+	Integrity string
+	Dispose   func()
 }
 
 func (e *EventInfo) Done() {
@@ -29,3 +115,25 @@ func (e *EventInfo) Done() {
 		e.Dispose()
 	}
 }
+
+// IsPlaceholder returns true if role means the event is detected but not found.
+func IsPlaceholder(role string) bool {
+	return strings.HasSuffix(role, PlaceholderMark)
+}
+
+// FoundRole returns role of the placeholder event when it is found.
+func FoundRole(role string) string {
+	if role == GapRole {
+		return ""
+	}
+	return strings.TrimSuffix(role, PlaceholderMark)
+}
+
+// ValidatorStats is a per-epoch summary of the validator events.
+type ValidatorStats struct {
+	Creator      idx.ValidatorID
+	Events       int
+	Atropoi      int
+	FirstLamport idx.Lamport
+	LastLamport  idx.Lamport
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+)
+
+// EpochInfo is the epoch boundaries and validators,
+// which make the epoch events a self-contained unit.
+type EpochInfo struct {
+	Epoch idx.Epoch
+	// FirstBlock is the first block of the epoch
+	FirstBlock idx.Block
+	// SealingBlock is the last block of the epoch, 0 if the epoch is not sealed yet
+	SealingBlock idx.Block
+	// Validators of the epoch sorted by id, nil if the source serves no validators
+	Validators []*EpochValidator
+	// Tail is true if the events which are not confirmed by any block are read also
+	Tail bool
+}
+
+// EpochValidator is a validator of the epoch.
+type EpochValidator struct {
+	ID     idx.ValidatorID
+	Weight *big.Int
+	PubKey string
+}
+
+// SortValidators sorts the validators by id.
+func SortValidators(validators []*EpochValidator) {
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].ID < validators[j].ID
+	})
+}
+
+// EpochStore stores the epoch infos.
+type EpochStore interface {
+	SetEpochInfo(*EpochInfo) error
+	// GetEpochInfo returns nil if the epoch is not stored.
+	GetEpochInfo(idx.Epoch) (*EpochInfo, error)
+}
+
+func (db *MemDb) SetEpochInfo(info *EpochInfo) error {
+	db.Lock()
+	defer db.Unlock()
+
+	stored := *info
+	db.epochs[info.Epoch] = &stored
+	return nil
+}
+
+func (db *MemDb) GetEpochInfo(epoch idx.Epoch) (*EpochInfo, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.epochs[epoch], nil
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ParseEventID parses event ID from the full "epoch:lamport:hex" form (see hash.Event.FullID())
+// or from the "0x" prefixed hex form.
+// TODO: mv to the "github.com/Fantom-foundation/lachesis-base/hash"
+func ParseEventID(s string) (id hash.Event, err error) {
+	if strings.HasPrefix(s, "0x") {
+		err = decodeEventID(id[:], s)
+		return
+	}
+
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 {
+		err = fmt.Errorf("invalid event id format: %s", s)
+		return
+	}
+
+	n, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return
+	}
+	copy(id[0:], idx.Epoch(n).Bytes())
+
+	n, err = strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return
+	}
+	copy(id[4:], idx.Lamport(n).Bytes())
+
+	err = decodeEventID(id[8:], "0x"+parts[2])
+	return
+}
+
+// decodeEventID decodes the hex of exactly the len(dst) bytes.
+func decodeEventID(dst []byte, hex string) error {
+	b, err := hexutil.Decode(hex)
+	if err != nil {
+		return fmt.Errorf("invalid event id hex %s: %v", hex, err)
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("invalid event id length %s: %d bytes instead of %d", hex, len(b), len(dst))
+	}
+	copy(dst, b)
+	return nil
+}
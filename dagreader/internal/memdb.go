@@ -0,0 +1,285 @@
+package internal
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+)
+
+// MemDb is an in-memory Db, the same as neo4j one but with no persistence.
+type MemDb struct {
+	events   map[hash.Event]*EventInfo
+	children map[hash.Event]hash.EventsSet
+	last     idx.Block
+	dagStart idx.Block
+	epochs   map[idx.Epoch]*EpochInfo
+
+	sync.RWMutex
+}
+
+func NewMemDb() *MemDb {
+	return &MemDb{
+		events:   make(map[hash.Event]*EventInfo),
+		children: make(map[hash.Event]hash.EventsSet),
+		epochs:   make(map[idx.Epoch]*EpochInfo),
+	}
+}
+
+// Load implements Sink interface.
+// The stored event is replaced with the loaded one (as neo4j MERGE does).
+func (db *MemDb) Load(events <-chan *EventInfo) error {
+	for info := range events {
+		db.Lock()
+		stored := *info
+		stored.Dispose = nil
+		id := info.Event.ID()
+		db.events[id] = &stored
+		for _, p := range info.Event.Parents() {
+			if db.children[p] == nil {
+				db.children[p] = hash.EventsSet{}
+			}
+			db.children[p].Add(id)
+		}
+		db.Unlock()
+
+		info.Done()
+	}
+	return nil
+}
+
+func (db *MemDb) SetLastBlock(n idx.Block) error {
+	db.Lock()
+	defer db.Unlock()
+
+	db.last = n
+	return nil
+}
+
+func (db *MemDb) GetLastBlock() (idx.Block, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.last, nil
+}
+
+func (db *MemDb) SetDagStart(n idx.Block) error {
+	db.Lock()
+	defer db.Unlock()
+
+	db.dagStart = n
+	return nil
+}
+
+func (db *MemDb) GetDagStart() (idx.Block, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.dagStart, nil
+}
+
+func (db *MemDb) HasEvent(e hash.Event) (bool, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	_, ok := db.events[e]
+	return ok, nil
+}
+
+func (db *MemDb) GetEvent(e hash.Event) (*EventInfo, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.events[e], nil
+}
+
+func (db *MemDb) GetChildren(e hash.Event) (hash.Events, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.filterStored(db.children[e].Slice()), nil
+}
+
+// GetBlockEvents returns events confirmed by the block.
+func (db *MemDb) GetBlockEvents(n idx.Block) ([]*EventInfo, error) {
+	return db.find(func(info *EventInfo) bool {
+		return info.Block == n
+	}), nil
+}
+
+// GetEpochEvents returns all the epoch events.
+func (db *MemDb) GetEpochEvents(epoch idx.Epoch) ([]*EventInfo, error) {
+	return db.find(func(info *EventInfo) bool {
+		return info.Event.Epoch() == epoch
+	}), nil
+}
+
+// GetPlaceholders returns events which are detected but not found yet.
+func (db *MemDb) GetPlaceholders() ([]*EventInfo, error) {
+	found := db.find(func(info *EventInfo) bool {
+		return IsPlaceholder(info.Role)
+	})
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Block < found[j].Block
+	})
+	return found, nil
+}
+
+// GetEpochs returns sorted epochs of the stored events.
+func (db *MemDb) GetEpochs() ([]idx.Epoch, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	set := make(map[idx.Epoch]struct{})
+	for id := range db.events {
+		set[id.Epoch()] = struct{}{}
+	}
+	epochs := make([]idx.Epoch, 0, len(set))
+	for epoch := range set {
+		epochs = append(epochs, epoch)
+	}
+	sort.Slice(epochs, func(i, j int) bool {
+		return epochs[i] < epochs[j]
+	})
+	return epochs, nil
+}
+
+// GetAtropoi returns atropos of each stored block.
+func (db *MemDb) GetAtropoi() (map[idx.Block]hash.Event, error) {
+	atropoi := make(map[idx.Block]hash.Event)
+	for _, info := range db.find(func(info *EventInfo) bool {
+		return strings.HasPrefix(info.Role, "atropos")
+	}) {
+		atropoi[info.Block] = info.Event.ID()
+	}
+	return atropoi, nil
+}
+
+// FindAncestors of event.
+func (db *MemDb) FindAncestors(e hash.Event, limit int) (hash.Events, error) {
+	return db.findRelatives(e, limit, func(e hash.Event) hash.Events {
+		if info, ok := db.events[e]; ok {
+			return info.Event.Parents()
+		}
+		return nil
+	}), nil
+}
+
+// FindDescendants of event.
+func (db *MemDb) FindDescendants(e hash.Event, limit int) (hash.Events, error) {
+	return db.findRelatives(e, limit, func(e hash.Event) hash.Events {
+		return db.children[e].Slice()
+	}), nil
+}
+
+// GetSnapshot returns the DAG as it is known when the block is decided.
+func (db *MemDb) GetSnapshot(n idx.Block) (*Snapshot, error) {
+	atropoi := db.find(func(info *EventInfo) bool {
+		return info.Block == n && strings.HasPrefix(info.Role, "atropos")
+	})
+	if len(atropoi) < 1 {
+		return nil, nil
+	}
+	atropos := atropoi[0]
+
+	known := db.find(func(info *EventInfo) bool {
+		return KnownAt(n, atropos.Event.ID(), info)
+	})
+	return NewSnapshot(n, atropos, known), nil
+}
+
+// GetEpochStats returns per validator statistics of the epoch events.
+func (db *MemDb) GetEpochStats(epoch idx.Epoch) ([]*ValidatorStats, error) {
+	events, _ := db.GetEpochEvents(epoch)
+	stats := make(map[idx.ValidatorID]*ValidatorStats)
+	for _, info := range events {
+		id := info.Event.ID()
+		creator := info.Event.Creator()
+		st, ok := stats[creator]
+		if !ok {
+			st = &ValidatorStats{
+				Creator:      creator,
+				FirstLamport: id.Lamport(),
+			}
+			stats[creator] = st
+		}
+		st.Events++
+		if strings.HasPrefix(info.Role, "atropos") {
+			st.Atropoi++
+		}
+		if st.FirstLamport > id.Lamport() {
+			st.FirstLamport = id.Lamport()
+		}
+		if st.LastLamport < id.Lamport() {
+			st.LastLamport = id.Lamport()
+		}
+	}
+
+	list := make([]*ValidatorStats, 0, len(stats))
+	for _, st := range stats {
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Creator < list[j].Creator
+	})
+	return list, nil
+}
+
+// find returns stored events, sorted by epoch and lamport, which match the filter.
+func (db *MemDb) find(filter func(*EventInfo) bool) []*EventInfo {
+	db.RLock()
+	defer db.RUnlock()
+
+	var found []*EventInfo
+	for _, info := range db.events {
+		if filter(info) {
+			found = append(found, info)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i].Event.ID(), found[j].Event.ID()
+		return bytes.Compare(a.Bytes(), b.Bytes()) < 0
+	})
+	return found
+}
+
+func (db *MemDb) findRelatives(e hash.Event, limit int, next func(hash.Event) hash.Events) hash.Events {
+	db.RLock()
+	defer db.RUnlock()
+
+	var (
+		found = hash.Events{}
+		was   = hash.EventsSet{e: struct{}{}}
+		queue = hash.Events{e}
+	)
+	for len(queue) > 0 {
+		e := queue[0]
+		queue = queue[1:]
+		for _, r := range db.filterStored(next(e)) {
+			if was.Contains(r) {
+				continue
+			}
+			was.Add(r)
+			found = append(found, r)
+			if limit > 0 && len(found) >= limit {
+				return found
+			}
+			queue = append(queue, r)
+		}
+	}
+	return found
+}
+
+// filterStored skips not stored events as neo4j relations does.
+func (db *MemDb) filterStored(ee hash.Events) hash.Events {
+	stored := make(hash.Events, 0, len(ee))
+	for _, e := range ee {
+		if _, ok := db.events[e]; ok {
+			stored = append(stored, e)
+		}
+	}
+	return stored
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"sort"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+)
+
+// Snapshot is the DAG as it is known when the block is decided.
+type Snapshot struct {
+	Block   idx.Block
+	Atropos *EventInfo
+	// Events is a count of the epoch events known as of the atropos
+	Events int
+	// Frontier is the known events which are not parents of the other known ones, sorted by creator
+	Frontier []*EventInfo
+	// Heads is the latest known event of each validator, sorted by creator
+	Heads []*EventInfo
+}
+
+// KnownAt returns true if the event is known when the block is decided: it is of the atropos epoch
+// and it is confirmed by the block or earlier, or it is not later than the atropos by lamport
+// (confirmed later or not confirmed yet).
+func KnownAt(block idx.Block, atropos hash.Event, info *EventInfo) bool {
+	id := info.Event.ID()
+	if id.Epoch() != atropos.Epoch() {
+		return false
+	}
+	return info.Block != UnconfirmedBlock && info.Block <= block || id.Lamport() <= atropos.Lamport()
+}
+
+// NewSnapshot makes snapshot of the block from the atropos epoch events which are KnownAt the block.
+func NewSnapshot(block idx.Block, atropos *EventInfo, known []*EventInfo) *Snapshot {
+	s := &Snapshot{
+		Block:   block,
+		Atropos: atropos,
+		Events:  len(known),
+	}
+
+	parents := make(hash.EventsSet, len(known))
+	heads := make(map[idx.ValidatorID]*EventInfo)
+	for _, info := range known {
+		for _, p := range info.Event.Parents() {
+			parents.Add(p)
+		}
+		// creator of placeholders is unknown
+		if IsPlaceholder(info.Role) {
+			continue
+		}
+		creator := info.Event.Creator()
+		// lamport grows along the self-parent chain, seq is unknown for some of the migrated events
+		if head, ok := heads[creator]; !ok || head.Event.Lamport() < info.Event.Lamport() {
+			heads[creator] = info
+		}
+	}
+
+	for _, info := range known {
+		if !parents.Contains(info.Event.ID()) {
+			s.Frontier = append(s.Frontier, info)
+		}
+	}
+	for _, head := range heads {
+		s.Heads = append(s.Heads, head)
+	}
+
+	for _, list := range [][]*EventInfo{s.Frontier, s.Heads} {
+		sort.Slice(list, func(i, j int) bool {
+			a, b := list[i].Event, list[j].Event
+			if a.Creator() != b.Creator() {
+				return a.Creator() < b.Creator()
+			}
+			return a.Lamport() < b.Lamport()
+		})
+	}
+
+	return s
+}
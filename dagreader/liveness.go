@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/logger"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/reader"
+)
+
+var (
+	alertsSilentFlag = cli.Uint64Flag{
+		Name:  "alerts.silent",
+		Usage: "alert when a validator emits no events for the number of blocks, 0 to disable",
+	}
+
+	alertsLagFlag = cli.Uint64Flag{
+		Name:  "alerts.lag",
+		Usage: "alert when lamport of a validator is behind the highest one of the epoch by the number, 0 to disable",
+	}
+
+	alertsUnreferencedFlag = cli.Uint64Flag{
+		Name:  "alerts.unreferenced",
+		Usage: "alert when events of a validator are not parents of the others for the number of blocks, 0 to disable",
+	}
+
+	alertsValidatorFlag = cli.StringSliceFlag{
+		Name:  "alerts.validator",
+		Usage: "thresholds of a validator instead of the default ones, as 'id:silent=N,lag=N,unreferenced=N' (omitted are default)",
+	}
+
+	alertsFileFlag = cli.StringFlag{
+		Name:  "alerts.file",
+		Usage: "file to append alerts to as newline delimited JSON",
+	}
+
+	alertsWebhookFlag = cli.StringFlag{
+		Name:  "alerts.webhook",
+		Usage: "URL to POST each alert to as JSON",
+	}
+)
+
+// Alert kinds.
+const (
+	alertSilent       = "silent"
+	alertLagging      = "lagging"
+	alertUnreferenced = "unreferenced"
+)
+
+// LivenessThresholds of the validator alerts, 0 to disable.
+type LivenessThresholds struct {
+	// Silent is a number of blocks with no events of the validator
+	Silent idx.Block
+	// Lag is a lamport distance from the highest event of the epoch to the last event of the validator
+	Lag idx.Lamport
+	// Unreferenced is a number of blocks with no events of the other validators which have the validator events as parents
+	Unreferenced idx.Block
+}
+
+// Enabled returns true if any alert is enabled.
+func (th LivenessThresholds) Enabled() bool {
+	return th.Silent > 0 || th.Lag > 0 || th.Unreferenced > 0
+}
+
+// LivenessConfig is the default and per validator thresholds.
+type LivenessConfig struct {
+	Default    LivenessThresholds
+	Validators map[idx.ValidatorID]LivenessThresholds
+}
+
+// Enabled returns true if any alert of any validator is enabled.
+func (cfg LivenessConfig) Enabled() bool {
+	if cfg.Default.Enabled() {
+		return true
+	}
+	for _, th := range cfg.Validators {
+		if th.Enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg LivenessConfig) thresholds(v idx.ValidatorID) LivenessThresholds {
+	if th, ok := cfg.Validators[v]; ok {
+		return th
+	}
+	return cfg.Default
+}
+
+func livenessFrom(cli *cli.Context) (LivenessConfig, error) {
+	cfg := LivenessConfig{
+		Default: LivenessThresholds{
+			Silent:       idx.Block(cli.Uint64(alertsSilentFlag.Name)),
+			Lag:          idx.Lamport(cli.Uint64(alertsLagFlag.Name)),
+			Unreferenced: idx.Block(cli.Uint64(alertsUnreferencedFlag.Name)),
+		},
+		Validators: make(map[idx.ValidatorID]LivenessThresholds),
+	}
+	for _, s := range cli.StringSlice(alertsValidatorFlag.Name) {
+		v, th, err := parseThresholds(s, cfg.Default)
+		if err != nil {
+			return cfg, fmt.Errorf("--%s %q: %w", alertsValidatorFlag.Name, s, err)
+		}
+		cfg.Validators[v] = th
+	}
+	return cfg, nil
+}
+
+// parseThresholds parses 'id:silent=N,lag=N,unreferenced=N', the omitted thresholds are the default ones.
+func parseThresholds(s string, th LivenessThresholds) (idx.ValidatorID, LivenessThresholds, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, th, fmt.Errorf("no validator id")
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, th, fmt.Errorf("validator id: %w", err)
+	}
+	for _, kv := range strings.Split(parts[1], ",") {
+		pair := strings.SplitN(kv, "=", 2)
+		if len(pair) != 2 {
+			return 0, th, fmt.Errorf("no value of %q", kv)
+		}
+		n, err := strconv.ParseUint(pair[1], 10, 32)
+		if err != nil {
+			return 0, th, fmt.Errorf("%s: %w", pair[0], err)
+		}
+		switch pair[0] {
+		case alertSilent:
+			th.Silent = idx.Block(n)
+		case "lag":
+			th.Lag = idx.Lamport(n)
+		case alertUnreferenced:
+			th.Unreferenced = idx.Block(n)
+		default:
+			return 0, th, fmt.Errorf("unknown threshold %q", pair[0])
+		}
+	}
+	return idx.ValidatorID(id), th, nil
+}
+
+// Alert is a JSON view of the validator liveness alert.
+type Alert struct {
+	Time      time.Time       `json:"time"`
+	Validator idx.ValidatorID `json:"validator"`
+	Kind      string          `json:"kind"`
+	// Resolved alert means the validator is alive again
+	Resolved bool      `json:"resolved,omitempty"`
+	Epoch    idx.Epoch `json:"epoch"`
+	Block    idx.Block `json:"block"`
+	// Since is the block of the last event (silent) or the last reference (unreferenced)
+	Since idx.Block   `json:"since,omitempty"`
+	Lag   idx.Lamport `json:"lag,omitempty"`
+}
+
+// AlertSender delivers alerts, its errors are logged only.
+type AlertSender interface {
+	Send(*Alert) error
+}
+
+// validatorLiveness is what is known about the validator in the current epoch.
+type validatorLiveness struct {
+	emitted    idx.Block
+	referenced idx.Block
+	// lamport is 0 if there are no events of the epoch yet
+	lamport idx.Lamport
+	alerts  map[string]bool
+}
+
+// LivenessSink watches the written events and alerts when a validator stops emitting,
+// falls behind in lamport or its events are not used as parents by the others.
+// Validators are tracked since the first block of the epoch if the epoch validators are known
+// (see SetValidators), since their first event otherwise. The alerts are checked when a block is read.
+type LivenessSink struct {
+	cfg     LivenessConfig
+	senders []AlertSender
+
+	// epochValidators are set by the reader before the epoch events are written
+	epochValidators map[idx.Epoch][]idx.ValidatorID
+	mu              sync.Mutex
+
+	epoch      idx.Epoch
+	block      idx.Block
+	maxLamport idx.Lamport
+	// seeded is the last epoch which validators are tracked from its start
+	seeded     idx.Epoch
+	validators map[idx.ValidatorID]*validatorLiveness
+	// creators of the epoch events to detect references
+	creators map[hash.Event]idx.ValidatorID
+
+	logger.Instance
+}
+
+// NewLivenessSink makes the sink.
+func NewLivenessSink(cfg LivenessConfig, senders ...AlertSender) *LivenessSink {
+	return &LivenessSink{
+		cfg:             cfg,
+		senders:         senders,
+		epochValidators: make(map[idx.Epoch][]idx.ValidatorID),
+		validators:      make(map[idx.ValidatorID]*validatorLiveness),
+		creators:        make(map[hash.Event]idx.ValidatorID),
+		Instance:        logger.New("liveness"),
+	}
+}
+
+// Load implements internal.Sink interface.
+func (s *LivenessSink) Load(events <-chan *internal.EventInfo) error {
+	for info := range events {
+		s.Add(info)
+		info.Done()
+	}
+	return nil
+}
+
+// Add tracks the event and checks the alerts when the event is of the next block.
+func (s *LivenessSink) Add(info *internal.EventInfo) {
+	e := info.Event
+	if e.Epoch() > s.epoch {
+		s.epoch = e.Epoch()
+		s.maxLamport = 0
+		s.creators = make(map[hash.Event]idx.ValidatorID)
+		for _, v := range s.validators {
+			v.lamport = 0
+		}
+	}
+	if info.Block > s.block {
+		if s.block != internal.UnconfirmedBlock {
+			s.check(s.block)
+		}
+		s.block = info.Block
+	}
+	if s.seeded < s.epoch && s.block != internal.UnconfirmedBlock {
+		s.seed()
+	}
+	// creator of placeholders is unknown
+	if internal.IsPlaceholder(info.Role) || e.Epoch() < s.epoch {
+		return
+	}
+
+	// the unconfirmed events are the latest ones
+	block := info.Block
+	if block == internal.UnconfirmedBlock {
+		block = s.block
+	}
+	v := s.validators[e.Creator()]
+	if v == nil {
+		v = &validatorLiveness{
+			referenced: block,
+			alerts:     make(map[string]bool),
+		}
+		s.validators[e.Creator()] = v
+	}
+	if v.emitted < block {
+		v.emitted = block
+	}
+	if v.lamport < e.Lamport() {
+		v.lamport = e.Lamport()
+	}
+	if s.maxLamport < e.Lamport() {
+		s.maxLamport = e.Lamport()
+	}
+
+	s.creators[e.ID()] = e.Creator()
+	for _, p := range e.Parents() {
+		creator, ok := s.creators[p]
+		if !ok || creator == e.Creator() {
+			continue
+		}
+		if ref := s.validators[creator]; ref != nil && ref.referenced < block {
+			ref.referenced = block
+		}
+	}
+}
+
+// seed tracks the epoch validators since the current block, the ones out of the epoch are not tracked any more.
+func (s *LivenessSink) seed() {
+	s.seeded = s.epoch
+	s.mu.Lock()
+	ids := s.epochValidators[s.epoch]
+	s.mu.Unlock()
+	if ids == nil {
+		return
+	}
+
+	set := make(map[idx.ValidatorID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+		if s.validators[id] == nil {
+			s.validators[id] = &validatorLiveness{
+				emitted:    s.block,
+				referenced: s.block,
+				alerts:     make(map[string]bool),
+			}
+		}
+	}
+	for id := range s.validators {
+		if !set[id] {
+			s.Log.Info("validator is out of the epoch", "validator", id, "epoch", s.epoch)
+			delete(s.validators, id)
+		}
+	}
+}
+
+// SetValidators sets the validators of the epoch, nil if they are unknown.
+// It is called by the reader (see reader.Config.OnValidators) before the epoch events are written.
+func (s *LivenessSink) SetValidators(epoch idx.Epoch, validators []*reader.EpochValidator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the sink can be behind the reader by an epoch
+	for e := range s.epochValidators {
+		if e+1 < epoch {
+			delete(s.epochValidators, e)
+		}
+	}
+	if validators == nil {
+		return
+	}
+	ids := make([]idx.ValidatorID, len(validators))
+	for i, v := range validators {
+		ids[i] = v.ID
+	}
+	s.epochValidators[epoch] = ids
+}
+
+// check raises and resolves alerts of the validators when the block is read.
+func (s *LivenessSink) check(block idx.Block) {
+	ids := make([]idx.ValidatorID, 0, len(s.validators))
+	for id := range s.validators {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		v, th := s.validators[id], s.cfg.thresholds(id)
+
+		s.update(id, v, &Alert{Kind: alertSilent, Block: block, Since: v.emitted},
+			th.Silent > 0 && block-v.emitted >= th.Silent)
+
+		s.update(id, v, &Alert{Kind: alertUnreferenced, Block: block, Since: v.referenced},
+			th.Unreferenced > 0 && block-v.referenced >= th.Unreferenced)
+
+		// silent validator has no lamport in the new epoch
+		var lag idx.Lamport
+		if v.lamport > 0 {
+			lag = s.maxLamport - v.lamport
+		}
+		s.update(id, v, &Alert{Kind: alertLagging, Block: block, Lag: lag},
+			th.Lag > 0 && lag >= th.Lag)
+	}
+}
+
+// update sends the alert when it is raised or resolved.
+func (s *LivenessSink) update(id idx.ValidatorID, v *validatorLiveness, a *Alert, raised bool) {
+	if v.alerts[a.Kind] == raised {
+		return
+	}
+	v.alerts[a.Kind] = raised
+
+	a.Time = time.Now()
+	a.Validator = id
+	a.Epoch = s.epoch
+	a.Resolved = !raised
+	if raised {
+		metrics.GetOrRegisterCounter("dagreader/alerts", nil).Inc(1)
+		s.Log.Warn("validator alert", "validator", id, "kind", a.Kind, "block", a.Block, "since", a.Since, "lag", a.Lag)
+	} else {
+		s.Log.Info("validator alert resolved", "validator", id, "kind", a.Kind, "block", a.Block)
+	}
+
+	for _, sender := range s.senders {
+		if err := sender.Send(a); err != nil {
+			s.Log.Error("send alert", "err", err)
+		}
+	}
+}
+
+// alertsFile appends alerts to the file, one JSON object per line.
+type alertsFile struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+func newAlertsFile(path string) (*alertsFile, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &alertsFile{
+		file: file,
+		enc:  json.NewEncoder(file),
+	}, nil
+}
+
+func (f *alertsFile) Send(a *Alert) error {
+	return f.enc.Encode(a)
+}
+
+func (f *alertsFile) Close() error {
+	return f.file.Close()
+}
+
+// alertsWebhook POSTs alerts to the URL in background, so a slow hook doesn't hold up the events.
+// Alerts above the queue limit are dropped.
+type alertsWebhook struct {
+	url    string
+	client *http.Client
+	queue  chan *Alert
+	done   sync.WaitGroup
+
+	logger.Instance
+}
+
+func newAlertsWebhook(url string) *alertsWebhook {
+	w := &alertsWebhook{
+		url:      url,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		queue:    make(chan *Alert, 100),
+		Instance: logger.New("webhook"),
+	}
+
+	w.done.Add(1)
+	go func() {
+		defer w.done.Done()
+		for a := range w.queue {
+			if err := w.post(a); err != nil {
+				w.Log.Error("post alert", "url", w.url, "err", err)
+			}
+		}
+	}()
+
+	return w
+}
+
+func (w *alertsWebhook) Send(a *Alert) error {
+	select {
+	case w.queue <- a:
+		return nil
+	default:
+		return fmt.Errorf("webhook queue is full, alert is dropped")
+	}
+}
+
+func (w *alertsWebhook) post(a *Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
+
+// Close sends the queued alerts.
+func (w *alertsWebhook) Close() {
+	close(w.queue)
+	w.done.Wait()
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/reader"
+)
+
+func TestLivenessAlerts(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		hooked []*Alert
+		mu     sync.Mutex
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := &Alert{}
+		require.NoError(json.NewDecoder(r.Body).Decode(a))
+		mu.Lock()
+		hooked = append(hooked, a)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "alerts.ndjson")
+	file, err := newAlertsFile(path)
+	require.NoError(err)
+	hook := newAlertsWebhook(server.URL)
+
+	def := LivenessThresholds{Silent: 2, Unreferenced: 3}
+	_, th3, err := parseThresholds("3:lag=4", def)
+	require.NoError(err)
+	sink := NewLivenessSink(LivenessConfig{
+		Default:    def,
+		Validators: map[idx.ValidatorID]LivenessThresholds{3: th3},
+	}, file, hook)
+
+	// validators 1 and 2 emit each block and refer to each other,
+	// validator 3 is silent in blocks 2-4 and nobody refers to it
+	var (
+		heads   = make(map[idx.ValidatorID]dag.Event)
+		emitter = func(block idx.Block, creator idx.ValidatorID, others ...idx.ValidatorID) {
+			var parents dag.Events
+			if head := heads[creator]; head != nil {
+				parents = append(parents, head)
+			}
+			for _, v := range others {
+				if head := heads[v]; head != nil {
+					parents = append(parents, head)
+				}
+			}
+			heads[creator] = livenessEvent(creator, parents)
+			sink.Add(&internal.EventInfo{Block: block, Event: heads[creator]})
+		}
+	)
+	for block := idx.Block(1); block <= 6; block++ {
+		emitter(block, 1, 2)
+		emitter(block, 2, 1)
+		if block == 1 || block == 5 {
+			emitter(block, 3)
+		}
+	}
+	emitter(7, 1, 2)
+	hook.Close()
+	require.NoError(file.Close())
+
+	type kind struct {
+		validator idx.ValidatorID
+		kind      string
+		resolved  bool
+		block     idx.Block
+	}
+	expected := []kind{
+		{3, alertSilent, false, 3},
+		{3, alertLagging, false, 3},
+		{3, alertUnreferenced, false, 4},
+		{3, alertSilent, true, 5},
+	}
+
+	var logged []*Alert
+	data, err := os.ReadFile(path)
+	require.NoError(err)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		a := &Alert{}
+		require.NoError(dec.Decode(a))
+		logged = append(logged, a)
+	}
+
+	for _, alerts := range [][]*Alert{logged, hooked} {
+		got := make([]kind, len(alerts))
+		for i, a := range alerts {
+			got[i] = kind{a.Validator, a.Kind, a.Resolved, a.Block}
+		}
+		require.Equal(expected, got)
+	}
+	require.Equal(idx.Block(1), logged[0].Since)
+}
+
+// alertsList collects the sent alerts.
+type alertsList []*Alert
+
+func (l *alertsList) Send(a *Alert) error {
+	*l = append(*l, a)
+	return nil
+}
+
+func TestLivenessEpochValidators(t *testing.T) {
+	require := require.New(t)
+
+	// validator 3 is of the epoch but emits nothing
+	var alerts alertsList
+	sink := NewLivenessSink(LivenessConfig{
+		Default: LivenessThresholds{Silent: 2, Unreferenced: 3},
+	}, &alerts)
+	sink.SetValidators(1, []*reader.EpochValidator{{ID: 1}, {ID: 2}, {ID: 3}})
+
+	heads := make(map[idx.ValidatorID]dag.Event)
+	for block := idx.Block(1); block <= 5; block++ {
+		for _, creator := range []idx.ValidatorID{1, 2} {
+			var parents dag.Events
+			for _, head := range heads {
+				parents = append(parents, head)
+			}
+			heads[creator] = livenessEvent(creator, parents)
+			sink.Add(&internal.EventInfo{Block: block, Event: heads[creator]})
+		}
+	}
+
+	type kind struct {
+		validator idx.ValidatorID
+		kind      string
+		block     idx.Block
+		since     idx.Block
+	}
+	var got []kind
+	for _, a := range alerts {
+		got = append(got, kind{a.Validator, a.Kind, a.Block, a.Since})
+	}
+	require.Equal([]kind{
+		{3, alertSilent, 3, 1},
+		{3, alertUnreferenced, 4, 1},
+	}, got)
+}
+
+func TestParseThresholds(t *testing.T) {
+	require := require.New(t)
+
+	def := LivenessThresholds{Silent: 10, Lag: 20, Unreferenced: 30}
+	v, th, err := parseThresholds("5:silent=1,unreferenced=3", def)
+	require.NoError(err)
+	require.Equal(idx.ValidatorID(5), v)
+	require.Equal(LivenessThresholds{Silent: 1, Lag: 20, Unreferenced: 3}, th)
+
+	for _, s := range []string{"5", "x:lag=1", "5:lag", "5:lag=x", "5:late=1"} {
+		_, _, err = parseThresholds(s, def)
+		require.Error(err, s)
+	}
+}
+
+// livenessEvent makes the next event of the creator.
+func livenessEvent(creator idx.ValidatorID, parents dag.Events) dag.Event {
+	e := &dag.MutableBaseEvent{}
+	e.SetEpoch(1)
+	e.SetCreator(creator)
+	var (
+		ids     hash.Events
+		lamport idx.Lamport
+		seq     idx.Event
+	)
+	for _, p := range parents {
+		ids = append(ids, p.ID())
+		lamport = idx.MaxLamport(lamport, p.Lamport())
+		if p.Creator() == creator {
+			seq = p.Seq()
+		}
+	}
+	e.SetLamport(lamport + 1)
+	e.SetSeq(seq + 1)
+	e.SetParents(ids)
+
+	var tail [24]byte
+	tail[0] = byte(creator)
+	tail[1] = byte(seq + 1)
+	return e.Build(tail)
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/urfave/cli"
 )
 
@@ -22,7 +24,7 @@ var (
 
 	dagStartFlag = cli.Uint64Flag{
 		Name:  "dagstart",
-		Usage: "genesis blocks with no DAG to skip them (4564024 for mainnet)",
+		Usage: "genesis blocks with no DAG to skip them (4564024 for mainnet), 0 to detect the first block with DAG",
 		Value: 1,
 	}
 )
@@ -33,22 +35,55 @@ func init() {
 
 	App.Version = version()
 	App.Flags = []cli.Flag{
+		configFileFlag,
 		operaApiUrlFlag,
 		dagStartFlag,
+		metricsEnabledFlag,
+		metricsPrometheusEndpointFlag,
+	}
+	// main exits with the code of the error, after App.Run is returned and the commands are cleaned up
+	App.ExitErrHandler = func(*cli.Context, error) {}
+	App.Before = func(ctx *cli.Context) error {
+		if err := applyGlobalConfig(ctx); err != nil {
+			return err
+		}
+		// before any metric is used, go-ethereum enables them for the bare --metrics arg only
+		if ctx.GlobalBool(metricsEnabledFlag.Name) {
+			metrics.Enabled = true
+		}
+		return setupPrometheus(ctx)
 	}
 	App.Commands = []cli.Command{
 		cmdSaveTo,
+		cmdServe,
+		cmdPlaceholders,
+		cmdCompare,
+		cmdDiff,
+		cmdMigrate,
+		cmdPrune,
+		cmdSnapshot,
+		cmdTraceTx,
+		cmdExport,
 	}
 }
 
 func main() {
-	if err := App.Run(os.Args); err != nil {
+	err := App.Run(os.Args)
+	var exit cli.ExitCoder
+	if errors.As(err, &exit) {
+		log.Error("Fail", "err", err)
+		os.Exit(exit.ExitCode())
+	}
+	if err != nil {
 		log.Crit("Fail", "err", err)
 	}
 }
 
 func cmd(f func(context.Context, *cli.Context) error) func(*cli.Context) error {
 	return func(cli *cli.Context) error {
+		if err := applyCommandConfig(cli); err != nil {
+			return err
+		}
 		ctx, cancel := jobContext()
 		defer cancel()
 		return f(ctx, cli)
@@ -64,11 +99,14 @@ func jobContext() (ctx context.Context, cancel func()) {
 	go func() {
 		select {
 		case <-sigs:
-			log.Warn("Interrupted")
+			log.Warn("Interrupted, stopping gracefully (interrupt again to exit immediately)")
 			cancel()
 		case <-ctx.Done():
 			log.Info("Finished")
+			return
 		}
+		<-sigs
+		log.Crit("Interrupted again, exit")
 	}()
 
 	return
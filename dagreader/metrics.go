@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+var (
+	metricsEnabledFlag = cli.BoolFlag{
+		Name:  "metrics",
+		Usage: "Enable metrics collection and reporting",
+	}
+
+	metricsPrometheusEndpointFlag = cli.StringFlag{
+		Name:  "metrics.prometheus.endpoint",
+		Usage: "Prometheus API endpoint to report metrics to",
+		Value: ":19090",
+	}
+)
+
+// The metrics are registered at the first use, so they are collected
+// if metrics are enabled by the flags or config.
+
+// eventsFetchedCounter is the same as of reader, compare also fetches events.
+func eventsFetchedCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/events/fetched", nil)
+}
+
+// rpcErrorsCounter returns RPC errors counter of the API method.
+func rpcErrorsCounter(method string) metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/rpc/errors/"+method, nil)
+}
+
+func setupPrometheus(ctx *cli.Context) error {
+	if !metrics.Enabled {
+		return nil
+	}
+
+	endpoint := ctx.GlobalString(metricsPrometheusEndpointFlag.Name)
+	log.Info("Starting Prometheus metrics exporter", "endpoint", endpoint)
+	go func() {
+		handler := prometheus.Handler(metrics.DefaultRegistry)
+		err := http.ListenAndServe(endpoint, handler)
+		if err != nil {
+			log.Error("Failure in running Prometheus metrics exporter", "err", err)
+		}
+	}()
+
+	return nil
+}
+
+// metricsSink counts the written events.
+type metricsSink struct{}
+
+// Load implements internal.Sink interface.
+func (metricsSink) Load(events <-chan *internal.EventInfo) error {
+	var (
+		sinkEventsCounter       = metrics.GetOrRegisterCounter("dagreader/sink/events", nil)
+		sinkPlaceholdersCounter = metrics.GetOrRegisterCounter("dagreader/sink/placeholders", nil)
+		sinkBlockGauge          = metrics.GetOrRegisterGauge("dagreader/sink/block", nil)
+	)
+	for info := range events {
+		sinkEventsCounter.Inc(1)
+		if internal.IsPlaceholder(info.Role) {
+			sinkPlaceholdersCounter.Inc(1)
+		}
+		if info.Block != internal.UnconfirmedBlock {
+			sinkBlockGauge.Update(int64(info.Block))
+		}
+		info.Done()
+	}
+	return nil
+}
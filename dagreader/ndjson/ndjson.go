@@ -0,0 +1,77 @@
+package ndjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/api"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// flushLimit is a max count of the written events which are not flushed to file yet.
+const flushLimit = 100
+
+// Sink appends events to the file, one JSON object (as api.Event) per line.
+type Sink struct {
+	file *os.File
+	busy sync.WaitGroup
+}
+
+func New(path string) (*Sink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sink{
+		file: file,
+	}, nil
+}
+
+// Load implements internal.Sink interface.
+// Events are acknowledged after they are flushed to the file.
+func (s *Sink) Load(events <-chan *internal.EventInfo) error {
+	s.busy.Add(1)
+	defer s.busy.Done()
+
+	var (
+		w       = bufio.NewWriter(s.file)
+		enc     = json.NewEncoder(w)
+		pending = make([]*internal.EventInfo, 0, flushLimit)
+	)
+
+	flush := func() error {
+		err := w.Flush()
+		if err != nil {
+			return err
+		}
+		for _, info := range pending {
+			info.Done()
+		}
+		pending = pending[:0]
+		return nil
+	}
+
+	for info := range events {
+		err := enc.Encode(api.NewEvent(info))
+		if err != nil {
+			return err
+		}
+		pending = append(pending, info)
+
+		if len(pending) >= flushLimit || len(events) == 0 {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// Close waits for Load is finished and closes the file.
+func (s *Sink) Close() error {
+	s.busy.Wait()
+	return s.file.Close()
+}
@@ -0,0 +1,63 @@
+package ndjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/api"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+func TestSink(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	sink, err := New(path)
+	require.NoError(err)
+
+	var (
+		events = make(chan *internal.EventInfo, 2)
+		done   int
+	)
+	for i := 1; i <= 2; i++ {
+		e := &dag.MutableBaseEvent{}
+		e.SetEpoch(2)
+		e.SetLamport(idx.Lamport(i))
+		events <- &internal.EventInfo{
+			Block: 5,
+			Role:  "atropos",
+			Event: e.Build([24]byte{byte(i)}),
+			Dispose: func() {
+				done++
+			},
+		}
+	}
+	close(events)
+
+	require.NoError(sink.Load(events))
+	require.NoError(sink.Close())
+	require.Equal(2, done)
+
+	file, err := os.Open(path)
+	require.NoError(err)
+	defer file.Close()
+
+	var got []api.Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e api.Event
+		require.NoError(json.Unmarshal(scanner.Bytes(), &e))
+		got = append(got, e)
+	}
+	require.Len(got, 2)
+	require.Equal("atropos", got[1].Role)
+	require.EqualValues(5, got[1].Block)
+	require.EqualValues(2, got[1].Lamport)
+}
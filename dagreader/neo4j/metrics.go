@@ -0,0 +1,27 @@
+package neo4j
+
+import (
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// The metrics are registered at the first use, so they are collected
+// if the application enables metrics before it opens the db.
+
+func eventsStoredCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/events/stored", nil)
+}
+
+func dbRetriesCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/db/retries", nil)
+}
+
+func prunedEventsCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/db/pruned", nil)
+}
+
+// writeHistogram returns the write latencies (in microseconds) of the kind: event, parents or state.
+func writeHistogram(kind string) metrics.Histogram {
+	return metrics.DefaultRegistry.GetOrRegister("dagreader/db/write/"+kind, func() metrics.Histogram {
+		return metrics.NewHistogram(metrics.NewExpDecaySample(1028, 0.015))
+	}).(metrics.Histogram)
+}
@@ -2,13 +2,12 @@ package neo4j
 
 import (
 	"encoding/json"
-	"strconv"
+	"math/big"
 	"strings"
 
-	"github.com/Fantom-foundation/go-opera/inter"
 	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
 	"github.com/Fantom-foundation/lachesis-base/inter/idx"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/neo4j/neo4j-go-driver/neo4j"
 
 	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
@@ -16,6 +15,9 @@ import (
 
 type fields map[string]interface{}
 
+// eventFields are the stored event properties to unmarshal EventInfo from, parents are queried apart.
+const eventFields = "e.block as block, e.role as role, e.id as id, e.creator as creator, e.seq as seq, e.integrity as integrity"
+
 func readFields(r neo4j.Record) fields {
 	ff := make(fields)
 	vals := r.Values()
@@ -57,12 +59,51 @@ func valToString(v interface{}) string {
 func marshal(x interface{}) fields {
 	switch v := x.(type) {
 	case *internal.EventInfo:
+		id := v.Event.ID()
+		// null removes the flag of the replaced version
+		var integrity interface{}
+		if v.Integrity != "" {
+			integrity = v.Integrity
+		}
 		return fields{
 			"block":   int64(v.Block),
 			"role":    v.Role,
-			"id":      eventId2str(v.Event.ID()),
+			"id":      eventId2str(id),
+			"epoch":   int64(id.Epoch()),
+			"lamport": int64(id.Lamport()),
+			"seq":     int64(v.Event.Seq()),
 			"creator": int64(v.Event.Creator()),
 			"parents": v.Event.Parents(),
+
+			"integrity": integrity,
+		}
+	case *internal.EpochInfo:
+		// validators are parallel lists, as properties are not nested
+		var ids, weights, pubkeys interface{}
+		if v.Validators != nil {
+			ii := make([]int64, len(v.Validators))
+			ww := make([]string, len(v.Validators))
+			pp := make([]string, len(v.Validators))
+			for i, val := range v.Validators {
+				ii[i] = int64(val.ID)
+				ww[i] = val.Weight.String()
+				pp[i] = val.PubKey
+			}
+			ids, weights, pubkeys = ii, ww, pp
+		}
+		return fields{
+			"first":      int64(v.FirstBlock),
+			"sealing":    int64(v.SealingBlock),
+			"tail":       v.Tail,
+			"validators": ids,
+			"weights":    weights,
+			"pubkeys":    pubkeys,
+		}
+	case *internal.PruneCutoff:
+		return fields{
+			"epoch":      int64(v.Epoch),
+			"blockEpoch": int64(v.BlockEpoch),
+			"lamport":    int64(v.Lamport),
 		}
 	default:
 		panic("unsupported type")
@@ -74,18 +115,51 @@ func unmarshal(ff fields, x interface{}) {
 	case *internal.EventInfo:
 		v.Block = idx.Block(ff["block"].(int64))
 		v.Role = ff["role"].(string)
+		if integrity, ok := ff["integrity"].(string); ok {
+			v.Integrity = integrity
+		}
 
-		event := &inter.MutableEventPayload{}
+		// base event keeps the stored ID, inter event would recalculate it
+		event := &dag.MutableBaseEvent{}
 		id := str2eventId(ff["id"].(string))
 		event.SetEpoch(id.Epoch())
 		event.SetLamport(id.Lamport())
-		event.SetID(eventIdTail(id))
 
 		event.SetCreator(idx.ValidatorID(ff["creator"].(int64)))
+		// seq is unknown (null) for some of the events written before schema version 2
+		if seq, ok := ff["seq"].(int64); ok {
+			event.SetSeq(idx.Event(seq))
+		}
 
 		event.SetParents(ff["parents"].(hash.Events))
 
-		v.Event = &event.Build().Event
+		v.Event = event.Build(eventIdTail(id))
+		return
+	case *internal.EpochInfo:
+		v.Epoch = idx.Epoch(ff["id"].(int64))
+		v.FirstBlock = idx.Block(ff["first"].(int64))
+		v.SealingBlock = idx.Block(ff["sealing"].(int64))
+		v.Tail = ff["tail"].(bool)
+		ids, ok := ff["validators"].([]interface{})
+		if !ok {
+			return
+		}
+		weights := ff["weights"].([]interface{})
+		pubkeys := ff["pubkeys"].([]interface{})
+		v.Validators = make([]*internal.EpochValidator, len(ids))
+		for i := range ids {
+			weight, _ := new(big.Int).SetString(weights[i].(string), 10)
+			v.Validators[i] = &internal.EpochValidator{
+				ID:     idx.ValidatorID(ids[i].(int64)),
+				Weight: weight,
+				PubKey: pubkeys[i].(string),
+			}
+		}
+		return
+	case *internal.PruneCutoff:
+		v.Epoch = idx.Epoch(ff["epoch"].(int64))
+		v.BlockEpoch = idx.Epoch(ff["blockEpoch"].(int64))
+		v.Lamport = idx.Lamport(ff["lamport"].(int64))
 		return
 	default:
 		panic("unsupported type")
@@ -96,25 +170,12 @@ func eventId2str(e hash.Event) string {
 	return e.FullID()
 }
 
-// TODO: mv to the "github.com/Fantom-foundation/lachesis-base/hash"
-func str2eventId(s string) (id hash.Event) {
-	parts := strings.SplitN(s, ":", 3)
-
-	n, err := strconv.ParseUint(parts[0], 10, 32)
+func str2eventId(s string) hash.Event {
+	id, err := internal.ParseEventID(s)
 	if err != nil {
 		panic(err)
 	}
-	copy(id[0:], idx.Epoch(n).Bytes())
-
-	n, err = strconv.ParseUint(parts[1], 10, 32)
-	if err != nil {
-		panic(err)
-	}
-	copy(id[4:], idx.Lamport(n).Bytes())
-
-	copy(id[8:], common.Hex2BytesFixed(parts[2], 32-4-4))
-
-	return
+	return id
 }
 
 func eventIdTail(e hash.Event) (r [24]byte) {
@@ -0,0 +1,49 @@
+package neo4j
+
+import (
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// SetEpochInfo stores the epoch boundaries and validators as (:Epoch {id: epoch}).
+func (s *Db) SetEpochInfo(info *internal.EpochInfo) error {
+	_, err := s.write("write epoch", func(ctx neo4j.Transaction) (interface{}, error) {
+		defer ctx.Close()
+
+		err := exec(ctx, `MERGE (p:Epoch %s) SET p += %s`, fields{
+			"id": int64(info.Epoch),
+		}, marshal(info))
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ctx.Commit()
+	})
+	return err
+}
+
+// GetEpochInfo returns the stored epoch info, nil if the epoch is not stored.
+func (s *Db) GetEpochInfo(epoch idx.Epoch) (*internal.EpochInfo, error) {
+	res, err := s.read("get epoch", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (p:Epoch %s) RETURN p.id as id, p.first as first, p.sealing as sealing, `+
+			`p.tail as tail, p.validators as validators, p.weights as weights, p.pubkeys as pubkeys`, fields{
+			"id": int64(epoch),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for cursor.Next() {
+			info := &internal.EpochInfo{}
+			unmarshal(readFields(cursor.Record()), info)
+			return info, nil
+		}
+		return nil, cursor.Err()
+	})
+	if err != nil || res == nil {
+		return nil, err
+	}
+	return res.(*internal.EpochInfo), nil
+}
@@ -0,0 +1,242 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// pruneBatch is a number of the events deleted by one transaction.
+const pruneBatch = 10000
+
+// Retention of the stored DAG, events outside of any window are pruned. 0 to keep all.
+type Retention struct {
+	// Epochs is a number of the last epochs to keep
+	Epochs idx.Epoch
+	// Blocks is a number of the last blocks (before the checkpoint) to keep,
+	// the unconfirmed events are pruned by epochs only
+	Blocks idx.Block
+}
+
+// Enabled returns true if anything is to be pruned.
+func (r Retention) Enabled() bool {
+	return r.Epochs > 0 || r.Blocks > 0
+}
+
+// cutoff returns the last epoch and the last block to prune, 0 if nothing.
+func (r Retention) cutoff(maxEpoch idx.Epoch, lastBlock idx.Block) (epoch idx.Epoch, block idx.Block) {
+	if r.Epochs > 0 && maxEpoch > r.Epochs {
+		epoch = maxEpoch - r.Epochs
+	}
+	if r.Blocks > 0 && lastBlock > r.Blocks {
+		block = lastBlock - r.Blocks
+	}
+	return
+}
+
+// PruneResult is what is pruned.
+type PruneResult struct {
+	// Epoch is the last pruned epoch, 0 if none
+	Epoch idx.Epoch
+	// Block is the last pruned block, 0 if none
+	Block idx.Block
+	// Events is a count of the deleted events
+	Events int64
+	// Sightings is a count of the deleted pending Seen nodes, the SEEN_BY relations are deleted with events
+	Sightings int64
+}
+
+// Prune deletes the events out of retention with their PARENT relations (and infos of the pruned epochs).
+// Retained events which lose their parents are marked as boundary (e.boundary = true).
+// The cutoff is stored first, so the reader doesn't read the pruned events again.
+func (s *Db) Prune(ctx context.Context, r Retention) (*PruneResult, error) {
+	res := &PruneResult{}
+	if !r.Enabled() {
+		return res, nil
+	}
+
+	maxEpoch, err := s.getMaxEpoch()
+	if err != nil {
+		return res, err
+	}
+	lastBlock, err := s.GetLastBlock()
+	if err != nil {
+		return res, err
+	}
+	res.Epoch, res.Block = r.cutoff(maxEpoch, lastBlock)
+	if res.Epoch == 0 && res.Block == 0 {
+		return res, nil
+	}
+	s.Log.Info("prune", "epoch", res.Epoch, "block", res.Block)
+	err = s.advancePruneCutoff(res.Epoch, res.Block)
+	if err != nil {
+		return res, err
+	}
+	defer s.cache.EventInfos.Purge()
+
+	pruned := func(v string) string {
+		return fmt.Sprintf("(%[1]s.epoch <= %[2]d OR (%[1]s.block > 0 AND %[1]s.block <= %[3]d))", v, res.Epoch, res.Block)
+	}
+	// boundary is set in the same transaction with the parent deletion, so it is never missed
+	query := fmt.Sprintf(`MATCH (p:Event) WHERE %s WITH p LIMIT %d `+
+		`OPTIONAL MATCH (e:Event)-[:PARENT]->(p) WHERE NOT %s SET e.boundary = true `+
+		`WITH collect(DISTINCT p) AS pruned FOREACH (p IN pruned | DETACH DELETE p) RETURN size(pruned)`,
+		pruned("p"), pruneBatch, pruned("e"))
+	for ctx.Err() == nil {
+		count, err := s.count("prune events", query)
+		if err != nil {
+			return res, err
+		}
+		if count == 0 {
+			break
+		}
+		res.Events += count
+		prunedEventsCounter().Inc(count)
+		s.Log.Info("pruned events", "total", res.Events)
+	}
+
+	if res.Epoch == 0 {
+		return res, ctx.Err()
+	}
+	// sightings are joined by id, which starts with epoch
+	query = fmt.Sprintf(`MATCH (s:Seen) WHERE toInteger(split(s.event, ":")[0]) <= %d WITH s LIMIT %d `+
+		`WITH collect(s) AS pruned FOREACH (s IN pruned | DELETE s) RETURN size(pruned)`,
+		res.Epoch, pruneBatch)
+	for ctx.Err() == nil {
+		count, err := s.count("prune sightings", query)
+		if err != nil {
+			return res, err
+		}
+		if count == 0 {
+			break
+		}
+		res.Sightings += count
+	}
+	if ctx.Err() != nil {
+		return res, ctx.Err()
+	}
+
+	err = s.exec("prune epochs", fmt.Sprintf(`MATCH (p:Epoch) WHERE p.id <= %d DELETE p`, res.Epoch))
+	return res, err
+}
+
+// GetPruneCutoff returns the stored cutoff of the pruned events, zero if nothing is pruned.
+func (s *Db) GetPruneCutoff() (internal.PruneCutoff, error) {
+	res, err := s.read("get prune cutoff", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (s:State %s) RETURN s.epoch as epoch, s.blockEpoch as blockEpoch, s.lamport as lamport`, fields{
+			"id": "pruned",
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for cursor.Next() {
+			cutoff := internal.PruneCutoff{}
+			unmarshal(readFields(cursor.Record()), &cutoff)
+			return cutoff, nil
+		}
+		return internal.PruneCutoff{}, cursor.Err()
+	})
+	if err != nil {
+		return internal.PruneCutoff{}, err
+	}
+	return res.(internal.PruneCutoff), nil
+}
+
+// advancePruneCutoff stores the cutoff of the epoch and the block to prune,
+// the block one is the Lamport of its atropos.
+func (s *Db) advancePruneCutoff(epoch idx.Epoch, block idx.Block) error {
+	cutoff, err := s.GetPruneCutoff()
+	if err != nil {
+		return err
+	}
+	if epoch > cutoff.Epoch {
+		cutoff.Epoch = epoch
+	}
+	if block > 0 {
+		atropos, found, err := s.getBlockAtropos(block)
+		if err != nil {
+			return err
+		}
+		// not found if the block is pruned before
+		if found && (atropos.Epoch() > cutoff.BlockEpoch ||
+			atropos.Epoch() == cutoff.BlockEpoch && atropos.Lamport() > cutoff.Lamport) {
+			cutoff.BlockEpoch = atropos.Epoch()
+			cutoff.Lamport = atropos.Lamport()
+		}
+	}
+
+	return s.exec("write prune cutoff", fmt.Sprintf(`MERGE (s:State {id:"pruned"}) SET s += %s`, marshal(&cutoff)))
+}
+
+// getBlockAtropos returns the highest event of the block, it is the block atropos.
+func (s *Db) getBlockAtropos(block idx.Block) (hash.Event, bool, error) {
+	res, err := s.read("get block atropos", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (e:Event) WHERE e.block = %d RETURN e.id ORDER BY e.lamport DESC LIMIT 1`, block)
+		if err != nil {
+			return nil, err
+		}
+
+		for cursor.Next() {
+			return str2eventId(cursor.Record().GetByIndex(0).(string)), nil
+		}
+		return nil, cursor.Err()
+	})
+	if err != nil || res == nil {
+		return hash.ZeroEvent, false, err
+	}
+	return res.(hash.Event), true, nil
+}
+
+func (s *Db) getMaxEpoch() (idx.Epoch, error) {
+	res, err := s.read("get max epoch", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (e:Event) RETURN max(e.epoch)`)
+		if err != nil {
+			return nil, err
+		}
+
+		var epoch idx.Epoch
+		if cursor.Next() {
+			if max, ok := cursor.Record().GetByIndex(0).(int64); ok {
+				epoch = idx.Epoch(max)
+			}
+		}
+		return epoch, cursor.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.(idx.Epoch), nil
+}
+
+// count runs the write query which returns a count.
+func (s *Db) count(op string, query string) (int64, error) {
+	res, err := s.write(op, func(ctx neo4j.Transaction) (interface{}, error) {
+		defer ctx.Close()
+
+		cursor, err := search(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		var count int64
+		if cursor.Next() {
+			count = cursor.Record().GetByIndex(0).(int64)
+		}
+		if err = cursor.Err(); err != nil {
+			return nil, err
+		}
+
+		return count, ctx.Commit()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.(int64), nil
+}
@@ -0,0 +1,283 @@
+package neo4j
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// FindAncestors of event.
+func (s *Db) FindAncestors(e hash.Event, limit int) (hash.Events, error) {
+	return s.findRelatives("MATCH (p:Event %s)-[:PARENT*]->(s:Event) RETURN DISTINCT s.id", e, limit)
+}
+
+// FindDescendants of event.
+func (s *Db) FindDescendants(e hash.Event, limit int) (hash.Events, error) {
+	return s.findRelatives("MATCH (p:Event)-[:PARENT*]->(s:Event %s) RETURN DISTINCT p.id", e, limit)
+}
+
+// GetChildren returns events which have the event as a parent.
+func (s *Db) GetChildren(e hash.Event) (hash.Events, error) {
+	return s.findRelatives("MATCH (p:Event)-[:PARENT]->(s:Event %s) RETURN p.id", e, 0)
+}
+
+func (s *Db) findRelatives(cypher string, e hash.Event, limit int) (hash.Events, error) {
+	if limit > 0 {
+		cypher = cypher + fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	res, err := s.read("find relatives", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, cypher, fields{
+			"id": eventId2str(e),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var relatives hash.Events
+		for cursor.Next() {
+			id := str2eventId(cursor.Record().GetByIndex(0).(string))
+			relatives = append(relatives, id)
+		}
+		return relatives, cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(hash.Events), nil
+}
+
+// GetBlockEvents returns events confirmed by the block.
+func (s *Db) GetBlockEvents(n idx.Block) ([]*internal.EventInfo, error) {
+	res, err := s.read("get block events", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (e:Event) WHERE e.block = %d RETURN `+eventFields,
+			int64(n),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var ff []fields
+		for cursor.Next() {
+			ff = append(ff, readFields(cursor.Record()))
+		}
+		if err = cursor.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, f := range ff {
+			e := str2eventId(f["id"].(string))
+			f["parents"], err = getParents(ctx, e)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return ff, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*internal.EventInfo
+	for _, ff := range res.([]fields) {
+		info := new(internal.EventInfo)
+		unmarshal(ff, info)
+		events = append(events, info)
+	}
+
+	return events, nil
+}
+
+// GetPlaceholders returns events which are detected but not found yet.
+func (s *Db) GetPlaceholders() ([]*internal.EventInfo, error) {
+	res, err := s.read("get placeholders", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (e:Event) WHERE e.role ENDS WITH %s RETURN `+eventFields+` ORDER BY e.block`,
+			valToString(internal.PlaceholderMark),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var events []*internal.EventInfo
+		for cursor.Next() {
+			ff := readFields(cursor.Record())
+			ff["parents"] = hash.Events(nil)
+
+			info := new(internal.EventInfo)
+			unmarshal(ff, info)
+			events = append(events, info)
+		}
+		return events, cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.([]*internal.EventInfo), nil
+}
+
+// GetEpochEvents returns all the epoch events.
+func (s *Db) GetEpochEvents(epoch idx.Epoch) ([]*internal.EventInfo, error) {
+	return s.findEvents("get epoch events", fmt.Sprintf("e.epoch = %d", epoch))
+}
+
+// GetSnapshot returns the DAG as it is known when the block is decided.
+func (s *Db) GetSnapshot(n idx.Block) (*internal.Snapshot, error) {
+	atropoi, err := s.findEvents("get atropos", fmt.Sprintf(`e.block = %d AND e.role STARTS WITH "atropos"`, n))
+	if err != nil || len(atropoi) < 1 {
+		return nil, err
+	}
+	atropos := atropoi[0]
+
+	// as internal.KnownAt
+	known, err := s.findEvents("get snapshot", fmt.Sprintf("e.epoch = %d AND ((e.block > 0 AND e.block <= %d) OR e.lamport <= %d)",
+		atropos.Event.Epoch(), n, atropos.Event.Lamport()))
+	if err != nil {
+		return nil, err
+	}
+
+	return internal.NewSnapshot(n, atropos, known), nil
+}
+
+// findEvents returns events (with parents) which match the cypher condition on e.
+func (s *Db) findEvents(op string, where string) ([]*internal.EventInfo, error) {
+	res, err := s.read(op, func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (e:Event) WHERE %s OPTIONAL MATCH (e)-[:PARENT]->(p:Event) RETURN `+eventFields+`, collect(p.id) as parents`,
+			where,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var events []*internal.EventInfo
+		for cursor.Next() {
+			ff := readFields(cursor.Record())
+			pp := ff["parents"].([]interface{})
+			parents := make(hash.Events, len(pp))
+			for i, p := range pp {
+				parents[i] = str2eventId(p.(string))
+			}
+			ff["parents"] = parents
+
+			info := new(internal.EventInfo)
+			unmarshal(ff, info)
+			events = append(events, info)
+		}
+		return events, cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.([]*internal.EventInfo), nil
+}
+
+// GetEpochStats returns per validator statistics of the epoch events.
+func (s *Db) GetEpochStats(epoch idx.Epoch) ([]*internal.ValidatorStats, error) {
+	res, err := s.read("get epoch stats", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (e:Event) WHERE e.epoch = %d RETURN e.lamport, e.creator, e.role`,
+			int64(epoch),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		stats := make(map[idx.ValidatorID]*internal.ValidatorStats)
+		for cursor.Next() {
+			vals := cursor.Record().Values()
+			lamport := idx.Lamport(vals[0].(int64))
+			creator := idx.ValidatorID(vals[1].(int64))
+			role := vals[2].(string)
+
+			st, ok := stats[creator]
+			if !ok {
+				st = &internal.ValidatorStats{
+					Creator:      creator,
+					FirstLamport: lamport,
+				}
+				stats[creator] = st
+			}
+			st.Events++
+			if strings.HasPrefix(role, "atropos") {
+				st.Atropoi++
+			}
+			if st.FirstLamport > lamport {
+				st.FirstLamport = lamport
+			}
+			if st.LastLamport < lamport {
+				st.LastLamport = lamport
+			}
+		}
+		if err = cursor.Err(); err != nil {
+			return nil, err
+		}
+
+		list := make([]*internal.ValidatorStats, 0, len(stats))
+		for _, st := range stats {
+			list = append(list, st)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Creator < list[j].Creator
+		})
+		return list, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.([]*internal.ValidatorStats), nil
+}
+
+// GetEpochs returns sorted epochs of the stored events.
+func (s *Db) GetEpochs() ([]idx.Epoch, error) {
+	res, err := s.read("get epochs", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (e:Event) RETURN DISTINCT e.epoch`)
+		if err != nil {
+			return nil, err
+		}
+
+		var epochs []idx.Epoch
+		for cursor.Next() {
+			epoch := cursor.Record().GetByIndex(0).(int64)
+			epochs = append(epochs, idx.Epoch(epoch))
+		}
+		sort.Slice(epochs, func(i, j int) bool {
+			return epochs[i] < epochs[j]
+		})
+		return epochs, cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.([]idx.Epoch), nil
+}
+
+// GetAtropoi returns atropos of each stored block.
+func (s *Db) GetAtropoi() (map[idx.Block]hash.Event, error) {
+	res, err := s.read("get atropoi", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (e:Event) WHERE e.role STARTS WITH "atropos" RETURN e.block, e.id`)
+		if err != nil {
+			return nil, err
+		}
+
+		atropoi := make(map[idx.Block]hash.Event)
+		for cursor.Next() {
+			vals := cursor.Record().Values()
+			atropoi[idx.Block(vals[0].(int64))] = str2eventId(vals[1].(string))
+		}
+		return atropoi, cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(map[idx.Block]hash.Event), nil
+}
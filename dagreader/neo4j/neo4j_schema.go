@@ -0,0 +1,334 @@
+package neo4j
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// ErrNewerSchema is returned on attempt to write db of a newer, unknown schema.
+var ErrNewerSchema = errors.New("db schema is newer than supported, upgrade dagreader")
+
+// migrationBatch is a number of the events updated by one transaction.
+const migrationBatch = 10000
+
+// Migration upgrades db schema to the Version. Its steps are idempotent,
+// so the interrupted migration is just applied again.
+type Migration struct {
+	Version     int64
+	Description string
+	steps       []step
+}
+
+type step struct {
+	description string
+	apply       func(s *Db) error
+}
+
+// Steps returns description of each migration step.
+func (m *Migration) Steps() []string {
+	ss := make([]string, len(m.steps))
+	for i, st := range m.steps {
+		ss[i] = st.description
+	}
+	return ss
+}
+
+// migrations are ordered by version, db written before the versioning has version 0.
+var migrations = []*Migration{
+	{
+		Version:     1,
+		Description: "unique event and block ids, checkpoint",
+		steps: []step{
+			ddl("CREATE CONSTRAINT ON (e:Event) ASSERT e.id IS UNIQUE"),
+			ddl("CREATE CONSTRAINT ON (b:Block) ASSERT b.id IS UNIQUE"),
+			ddl("CREATE INDEX ON :Seen(event)"),
+			update("create checkpoint", `MERGE (s:State {id:"last"}) ON CREATE SET s.block = 1`),
+		},
+	},
+	{
+		Version:     2,
+		Description: "typed and indexed epoch, lamport, seq, creator and block of events",
+		steps: []step{
+			ddl("CREATE INDEX ON :Event(epoch)"),
+			ddl("CREATE INDEX ON :Event(lamport)"),
+			ddl("CREATE INDEX ON :Event(seq)"),
+			ddl("CREATE INDEX ON :Event(creator)"),
+			ddl("CREATE INDEX ON :Event(block)"),
+			{"set epoch and lamport parsed from event id", (*Db).addEpochLamport},
+			{"derive seq from self-parents", (*Db).addSeq},
+		},
+	},
+	{
+		Version:     3,
+		Description: "single checkpoint",
+		steps: []step{
+			update("remove duplicate checkpoints, keep the latest",
+				`MATCH (s:State {id:"last"}) WITH s ORDER BY s.block DESC SKIP 1 DELETE s`),
+			ddl("CREATE CONSTRAINT ON (s:State) ASSERT s.id IS UNIQUE"),
+		},
+	},
+	{
+		Version:     4,
+		Description: "unique epoch infos",
+		steps: []step{
+			ddl("CREATE CONSTRAINT ON (p:Epoch) ASSERT p.id IS UNIQUE"),
+		},
+	},
+	{
+		Version:     5,
+		Description: "sightings as event relations",
+		steps: []step{
+			ddl("CREATE CONSTRAINT ON (n:Node) ASSERT n.url IS UNIQUE"),
+			update("link sightings of the stored events", fmt.Sprintf(linkSeen, "")),
+		},
+	},
+}
+
+// LatestSchemaVersion returns the schema version which db is upgraded to.
+func LatestSchemaVersion() int64 {
+	return migrations[len(migrations)-1].Version
+}
+
+// ddl step creates index or constraint, the existing one is kept.
+func ddl(query string) step {
+	return step{query, func(s *Db) error {
+		err := s.exec("migrate", query)
+		if err != nil && isSchemaExists(err) {
+			s.Log.Debug("schema rule exists", "query", query)
+			return nil
+		}
+		return err
+	}}
+}
+
+// update step runs the write query.
+func update(description, query string) step {
+	return step{description, func(s *Db) error {
+		return s.exec("migrate", query)
+	}}
+}
+
+// isSchemaExists returns true if DDL fails as the same index or constraint exists already.
+func isSchemaExists(err error) bool {
+	return strings.Contains(err.Error(), "Neo.ClientError.Schema.") && strings.Contains(err.Error(), "AlreadyExists")
+}
+
+// SchemaVersion returns the stored schema version.
+func (s *Db) SchemaVersion() (int64, error) {
+	res, err := s.read("get schema version", func(ctx neo4j.Transaction) (interface{}, error) {
+		cursor, err := search(ctx, `MATCH (s:State %s) RETURN s.version`, fields{
+			"id": "schema",
+		})
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Next() {
+			return cursor.Record().GetByIndex(0).(int64), nil
+		}
+		return int64(0), cursor.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.(int64), nil
+}
+
+// PendingMigrations returns the migrations to upgrade db to the latest schema.
+func (s *Db) PendingMigrations() ([]*Migration, error) {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if version > LatestSchemaVersion() {
+		return nil, fmt.Errorf("%w: version %d, supported %d", ErrNewerSchema, version, LatestSchemaVersion())
+	}
+
+	var pending []*Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies the pending migrations in order, the version is stored after each of them.
+func (s *Db) Migrate() error {
+	pending, err := s.PendingMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		s.Log.Info("migrate db schema", "version", m.Version, "description", m.Description)
+		for _, st := range m.steps {
+			s.Log.Info("migration step", "version", m.Version, "step", st.description)
+			err = st.apply(s)
+			if err != nil {
+				return fmt.Errorf("migration %d: %w", m.Version, err)
+			}
+		}
+		err = s.setSchemaVersion(m.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Db) setSchemaVersion(version int64) error {
+	return s.exec("write schema version", fmt.Sprintf(`MERGE (s:State {id:"schema"}) SET s.version = %d`, version))
+}
+
+// exec runs the single write query.
+func (s *Db) exec(op string, query string) error {
+	_, err := s.write(op, func(ctx neo4j.Transaction) (interface{}, error) {
+		defer ctx.Close()
+
+		err := exec(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ctx.Commit()
+	})
+	return err
+}
+
+// addEpochLamport sets epoch and lamport parsed from the event id.
+func (s *Db) addEpochLamport() error {
+	var total int64
+	for {
+		count, err := s.count("migrate epoch and lamport", fmt.Sprintf(`MATCH (e:Event) WHERE e.epoch IS NULL WITH e LIMIT %d `+
+			`SET e.epoch = toInteger(split(e.id, ":")[0]), e.lamport = toInteger(split(e.id, ":")[1]) `+
+			`RETURN count(e)`, migrationBatch))
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			break
+		}
+		total += count
+		s.Log.Info("migrate epoch and lamport", "events", total)
+	}
+	return nil
+}
+
+// addSeq sets seq derived from the self-parent chain of each epoch.
+func (s *Db) addSeq() error {
+	epochs, err := s.GetEpochs()
+	if err != nil {
+		return err
+	}
+	for _, epoch := range epochs {
+		events, err := s.GetEpochEvents(epoch)
+		if err != nil {
+			return err
+		}
+		seqs := eventSeqs(events)
+		s.Log.Info("migrate seq", "epoch", epoch, "events", len(events), "unknown", len(events)-len(seqs))
+
+		rows := make([]string, 0, migrationBatch)
+		for e, seq := range seqs {
+			rows = append(rows, fields{
+				"id":  eventId2str(e),
+				"seq": int64(seq),
+			}.String())
+			if len(rows) >= migrationBatch {
+				if err = s.setSeqs(rows); err != nil {
+					return err
+				}
+				rows = rows[:0]
+			}
+		}
+		if err = s.setSeqs(rows); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Db) setSeqs(rows []string) error {
+	if len(rows) < 1 {
+		return nil
+	}
+	_, err := s.write("migrate seq", func(ctx neo4j.Transaction) (interface{}, error) {
+		defer ctx.Close()
+
+		err := exec(ctx, `UNWIND [%s] AS row MATCH (e:Event {id: row.id}) SET e.seq = row.seq`,
+			strings.Join(rows, ","))
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ctx.Commit()
+	})
+	return err
+}
+
+// eventSeqs derives seq of the epoch events from their self-parents. Placeholders
+// have seq 0 as their creator is unknown, so seq is unknown for their self-children.
+func eventSeqs(events []*internal.EventInfo) map[hash.Event]idx.Event {
+	infos := make(map[hash.Event]*internal.EventInfo, len(events))
+	for _, info := range events {
+		infos[info.Event.ID()] = info
+	}
+
+	seqs := make(map[hash.Event]idx.Event, len(events))
+	unknown := make(map[hash.Event]bool)
+	var seqOf func(info *internal.EventInfo) (idx.Event, bool)
+	seqOf = func(info *internal.EventInfo) (idx.Event, bool) {
+		id := info.Event.ID()
+		if seq, ok := seqs[id]; ok {
+			return seq, true
+		}
+		if unknown[id] {
+			return 0, false
+		}
+		if internal.IsPlaceholder(info.Role) {
+			seqs[id] = 0
+			return 0, true
+		}
+
+		var seq idx.Event = 1
+		for _, p := range info.Event.Parents() {
+			parent := infos[p]
+			if parent == nil || internal.IsPlaceholder(parent.Role) {
+				// may be the self-parent
+				seq = 0
+				continue
+			}
+			if parent.Event.Creator() != info.Event.Creator() {
+				continue
+			}
+			selfSeq, known := seqOf(parent)
+			if !known {
+				unknown[id] = true
+				return 0, false
+			}
+			seq = selfSeq + 1
+			break
+		}
+		if seq == 0 {
+			unknown[id] = true
+			return 0, false
+		}
+
+		seqs[id] = seq
+		return seq, true
+	}
+
+	for _, info := range events {
+		seqOf(info)
+	}
+	return seqs
+}
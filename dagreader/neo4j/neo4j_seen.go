@@ -0,0 +1,91 @@
+package neo4j
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/neo4j/neo4j-go-driver/neo4j"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// linkSeen moves the pending sightings (of the pattern) of the stored events to their SEEN_BY relations.
+const linkSeen = `MATCH (s:Seen %s) MATCH (e:Event {id: s.event}) MERGE (n:Node {url: s.node}) ` +
+	`MERGE (e)-[r:SEEN_BY]->(n) ON CREATE SET r.at = s.at DELETE s`
+
+// SetSeen stores the first time the node serves the event as (:Event)-[:SEEN_BY {at}]->(:Node {url}).
+// The event may be not stored yet, then the sighting is pending as (:Seen {node, event, at})
+// until the event is written.
+func (s *Db) SetSeen(node string, e hash.Event, at time.Time) error {
+	_, err := s.write("write sighting", func(ctx neo4j.Transaction) (interface{}, error) {
+		defer ctx.Close()
+
+		ms := at.UnixNano() / int64(time.Millisecond)
+		cursor, err := search(ctx, `MATCH (e:Event %s) MERGE (n:Node %s) `+
+			`MERGE (e)-[r:SEEN_BY]->(n) ON CREATE SET r.at = %d RETURN count(r)`, fields{
+			"id": eventId2str(e),
+		}, fields{
+			"url": node,
+		}, ms)
+		if err != nil {
+			return nil, err
+		}
+		var linked int64
+		if cursor.Next() {
+			linked = cursor.Record().GetByIndex(0).(int64)
+		}
+		if err = cursor.Err(); err != nil {
+			return nil, err
+		}
+
+		if linked == 0 {
+			err = exec(ctx, `MERGE (s:Seen %s) ON CREATE SET s.at = %d`, fields{
+				"node":  node,
+				"event": eventId2str(e),
+			}, ms)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, ctx.Commit()
+	})
+	return err
+}
+
+// GetSeen returns the first times the nodes serve the event, ordered by time.
+func (s *Db) GetSeen(e hash.Event) ([]*internal.Sighting, error) {
+	res, err := s.read("get sightings", func(ctx neo4j.Transaction) (interface{}, error) {
+		id := eventId2str(e)
+		// the pending ones are of the event which is written concurrently
+		cursor, err := search(ctx, `MATCH (e:Event %s)-[r:SEEN_BY]->(n:Node) RETURN n.url AS url, r.at AS at `+
+			`UNION MATCH (s:Seen %s) RETURN s.node AS url, s.at AS at`, fields{
+			"id": id,
+		}, fields{
+			"event": id,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var seen []*internal.Sighting
+		for cursor.Next() {
+			r := cursor.Record()
+			seen = append(seen, &internal.Sighting{
+				Node: r.GetByIndex(0).(string),
+				At:   time.Unix(0, r.GetByIndex(1).(int64)*int64(time.Millisecond)),
+			})
+		}
+		return seen, cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := res.([]*internal.Sighting)
+	sort.Slice(seen, func(i, j int) bool {
+		return seen[i].At.Before(seen[j].At)
+	})
+	return seen, nil
+}
@@ -1,10 +1,14 @@
 package neo4j
 
 import (
+	"math/big"
+	"math/rand"
 	"testing"
 
-	"github.com/Fantom-foundation/go-opera/inter"
 	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag/tdag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
 	"github.com/stretchr/testify/require"
 
 	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
@@ -13,13 +17,18 @@ import (
 func TestNeo4jMarshaling(t *testing.T) {
 	require := require.New(t)
 
-	event := &inter.MutableEventPayload{}
+	event := &dag.MutableBaseEvent{}
+	event.SetEpoch(2)
+	event.SetLamport(5)
 	event.SetCreator(3)
+	event.SetSeq(4)
+	event.SetParents(hash.FakeEvents(2))
 
 	info0 := &internal.EventInfo{
-		Block: 10,
-		Role:  "root",
-		Event: &event.Build().Event,
+		Block:     10,
+		Role:      "root",
+		Event:     event.Build([24]byte{1, 2, 3}),
+		Integrity: "sig",
 	}
 	ff := marshal(info0)
 
@@ -29,6 +38,77 @@ func TestNeo4jMarshaling(t *testing.T) {
 	require.Equal(info0, info1)
 }
 
+func TestEpochMarshaling(t *testing.T) {
+	require := require.New(t)
+
+	info0 := &internal.EpochInfo{
+		Epoch:        3,
+		FirstBlock:   10,
+		SealingBlock: 20,
+		Tail:         true,
+		Validators: []*internal.EpochValidator{
+			{ID: 1, Weight: big.NewInt(100), PubKey: "0xc001"},
+			{ID: 2, Weight: big.NewInt(200), PubKey: "0xc002"},
+		},
+	}
+	ff := marshal(info0)
+
+	// driver reads lists as []interface{}
+	ff["id"] = int64(info0.Epoch)
+	for _, key := range []string{"validators", "weights", "pubkeys"} {
+		var list []interface{}
+		switch vv := ff[key].(type) {
+		case []int64:
+			for _, v := range vv {
+				list = append(list, v)
+			}
+		case []string:
+			for _, v := range vv {
+				list = append(list, v)
+			}
+		}
+		ff[key] = list
+	}
+
+	info1 := &internal.EpochInfo{}
+	unmarshal(ff, info1)
+	require.Equal(info0, info1)
+
+	ff = marshal(&internal.EpochInfo{Epoch: 4})
+	ff["id"] = int64(4)
+	info1 = &internal.EpochInfo{}
+	unmarshal(ff, info1)
+	require.Equal(&internal.EpochInfo{Epoch: 4}, info1)
+}
+
+func TestPruneCutoffMarshaling(t *testing.T) {
+	require := require.New(t)
+
+	cutoff0 := &internal.PruneCutoff{Epoch: 3, BlockEpoch: 5, Lamport: 42}
+	ff := marshal(cutoff0)
+
+	cutoff1 := &internal.PruneCutoff{}
+	unmarshal(ff, cutoff1)
+	require.Equal(cutoff0, cutoff1)
+
+	for i, c := range []struct {
+		epoch   idx.Epoch
+		lamport idx.Lamport
+		pruned  bool
+	}{
+		{3, 100, true},
+		{4, 100, true},
+		{5, 42, true},
+		{5, 43, false},
+		{6, 1, false},
+	} {
+		var id hash.Event
+		copy(id[0:4], c.epoch.Bytes())
+		copy(id[4:8], c.lamport.Bytes())
+		require.Equal(c.pruned, cutoff1.Pruned(id), i)
+	}
+}
+
 func TestEventIdParsing(t *testing.T) {
 	require := require.New(t)
 	for i, e0 := range []hash.Event{
@@ -44,3 +124,79 @@ func TestEventIdParsing(t *testing.T) {
 		require.Equal(e0, e1, i, s)
 	}
 }
+
+func TestEventSeqs(t *testing.T) {
+	require := require.New(t)
+
+	var events []*internal.EventInfo
+	tdag.ForEachRandEvent(tdag.GenNodes(3), 10, 2, rand.New(rand.NewSource(0)), tdag.ForEachEvent{
+		Process: func(e dag.Event, name string) {
+			events = append(events, &internal.EventInfo{Event: e})
+		},
+	})
+
+	seqs := eventSeqs(events)
+	require.Len(seqs, len(events))
+	for _, info := range events {
+		require.Equal(info.Event.Seq(), seqs[info.Event.ID()], info.Event.ID().String())
+	}
+
+	// self-children of the placeholder have unknown seq
+	var placeholder *internal.EventInfo
+	for _, info := range events {
+		if info.Event.Seq() == 5 {
+			placeholder = info
+			break
+		}
+	}
+	placeholder.Role = "atropos" + internal.PlaceholderMark
+
+	seqs = eventSeqs(events)
+	for _, info := range events {
+		seq, known := seqs[info.Event.ID()]
+		switch {
+		case info == placeholder:
+			require.True(known)
+			require.Zero(seq)
+		case info.Event.Creator() == placeholder.Event.Creator() && info.Event.Seq() > 5:
+			require.False(known, info.Event.ID().String())
+		default:
+			require.Equal(info.Event.Seq(), seq, info.Event.ID().String())
+		}
+	}
+}
+
+func TestMigrationsOrder(t *testing.T) {
+	require := require.New(t)
+
+	var version int64
+	for _, m := range migrations {
+		require.Equal(version+1, m.Version, "versions are consecutive")
+		require.NotEmpty(m.Steps())
+		version = m.Version
+	}
+	require.Equal(version, LatestSchemaVersion())
+}
+
+func TestRetentionCutoff(t *testing.T) {
+	require := require.New(t)
+
+	for i, c := range []struct {
+		retention Retention
+		maxEpoch  idx.Epoch
+		lastBlock idx.Block
+		epoch     idx.Epoch
+		block     idx.Block
+	}{
+		{Retention{}, 10, 100, 0, 0},
+		{Retention{Epochs: 3}, 10, 100, 7, 0},
+		{Retention{Epochs: 10}, 10, 100, 0, 0},
+		{Retention{Blocks: 30}, 10, 100, 0, 70},
+		{Retention{Blocks: 300}, 10, 100, 0, 0},
+		{Retention{Epochs: 1, Blocks: 1}, 10, 100, 9, 99},
+	} {
+		epoch, block := c.retention.cutoff(c.maxEpoch, c.lastBlock)
+		require.Equal(c.epoch, epoch, i)
+		require.Equal(c.block, block, i)
+	}
+}
@@ -0,0 +1,415 @@
+package reader
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/logger"
+	"github.com/Fantom-foundation/lachesis-base/eventcheck"
+	"github.com/Fantom-foundation/lachesis-base/gossip/dagordering"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/Fantom-foundation/lachesis-base/utils/cachescale"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// BufferConfig is a set of EventsBuffer limits.
+type BufferConfig struct {
+	// Limit of the incomplete events, the oldest are dropped above it
+	Limit dag.Metric
+	// GapTimeout after which missing parents of the incomplete events are re-requested,
+	// and after one more timeout are replaced with gap markers. 0 to wait forever.
+	GapTimeout time.Duration
+	// Verify checks lamport, seq and epoch of the completed events against their parents,
+	// the failed events are flagged with EventInfo.Integrity (not dropped)
+	Verify bool
+}
+
+// DefaultBufferConfig returns default EventsBuffer limits.
+func DefaultBufferConfig() BufferConfig {
+	return BufferConfig{
+		Limit: dag.Metric{
+			Num:  3000,
+			Size: cachescale.Identity.U64(10 * opt.MiB),
+		},
+		GapTimeout: time.Minute,
+	}
+}
+
+// cutoffTTL is how long the db prune cutoff is cached.
+const cutoffTTL = 10 * time.Second
+
+// checkpointHolder keeps the checkpoint before the blocks of the events which are not written yet (see Fanout).
+type checkpointHolder interface {
+	hold(idx.Block)
+	release(idx.Block)
+}
+
+type EventsBuffer struct {
+	db     internal.Db
+	config BufferConfig
+	// holder gets the blocks of the buffered events, which are held until the events are written
+	holder checkpointHolder
+
+	events struct {
+		info      map[hash.Event]*internal.EventInfo
+		processed map[idx.Epoch]map[hash.Event]dag.Event
+		// since is a time when the event is pushed
+		since map[hash.Event]time.Time
+		// requested is a time when the missing parent is re-requested
+		requested map[hash.Event]time.Time
+		// dropped events with their blocks, which are not written (yet)
+		dropped map[hash.Event]idx.Block
+	}
+
+	// cutoff of the db pruned events, which are refreshed at most each cutoffTTL
+	cutoff struct {
+		internal.PruneCutoff
+		at time.Time
+	}
+
+	ordering *dagordering.EventsBuffer
+
+	output chan *internal.EventInfo
+	busy   sync.WaitGroup
+	sync.RWMutex
+
+	failure
+	logger.Instance
+}
+
+func NewEventsBuffer(db internal.Db, config BufferConfig) *EventsBuffer {
+	count := int(config.Limit.Num)
+
+	s := &EventsBuffer{
+		db:       db,
+		config:   config,
+		output:   make(chan *internal.EventInfo, 10),
+		failure:  newFailure(),
+		Instance: logger.New("buffer"),
+	}
+
+	s.events.processed = make(map[idx.Epoch]map[hash.Event]dag.Event, 3)
+	s.events.info = make(map[hash.Event]*internal.EventInfo, count)
+	s.events.since = make(map[hash.Event]time.Time, count)
+	s.events.requested = make(map[hash.Event]time.Time)
+	s.events.dropped = make(map[hash.Event]idx.Block)
+	s.holder, _ = db.(checkpointHolder)
+
+	s.busy.Add(1)
+	go func() {
+		defer s.busy.Done()
+		if err := db.Load(s.output); err != nil {
+			s.fail(err)
+		}
+	}()
+
+	s.ordering = dagordering.New(config.Limit, dagordering.Callback{
+		Process: func(e dag.Event) error {
+			id := e.ID()
+			epoch := id.Epoch()
+			info := s.events.info[id]
+			if info == nil {
+				panic("event info not found")
+			}
+			if _, exists := s.events.processed[epoch]; !exists {
+				s.events.processed[epoch] = make(map[hash.Event]dag.Event, count)
+				delete(s.events.processed, epoch-2)
+			}
+
+			if IntegrityFailed(info.Integrity) {
+				unverifiedCounter().Inc(1)
+			}
+			s.Log.Debug("completed event", "id", id)
+			if s.holder != nil {
+				// held until the event is written
+				block, dispose := info.Block, info.Dispose
+				info.Dispose = func() {
+					s.holder.release(block)
+					if dispose != nil {
+						dispose()
+					}
+				}
+			}
+			s.output <- info
+			s.events.processed[epoch][id] = e
+			s.forget(id)
+			delete(s.events.requested, id)
+			if block, dropped := s.events.dropped[id]; dropped {
+				s.release(block)
+				delete(s.events.dropped, id)
+			}
+			bufferBacklogGauge().Update(int64(len(s.events.info)))
+
+			return nil
+		},
+
+		Released: func(e dag.Event, peer string, err error) {
+			id := e.ID()
+			if s.ordering.IsBuffered(id) {
+				// duplicate of the incomplete event
+				return
+			}
+			info := s.events.info[id]
+			if errors.Is(err, eventcheck.ErrSpilledEvent) {
+				bufferSpilledCounter().Inc(1)
+				s.Log.Warn("incomplete event is dropped", "id", id)
+				if info != nil {
+					// still held, as it is read again on the next run
+					if block, dropped := s.events.dropped[id]; dropped {
+						s.release(block)
+					}
+					s.events.dropped[id] = info.Block
+				}
+			} else if info != nil {
+				s.release(info.Block)
+			}
+			s.forget(id)
+		},
+
+		Exists: s.exists,
+
+		Get: func(e hash.Event) dag.Event {
+			ee, ok := s.events.processed[e.Epoch()]
+			if ok {
+				if event, exists := ee[e]; exists {
+					return event
+				}
+			}
+
+			if !ok || len(s.events.processed) < 2 {
+				info, err := s.db.GetEvent(e)
+				if err != nil {
+					s.storageFailure(err)
+					return nil
+				}
+				if info != nil {
+					return info.Event
+				}
+				// pruned parent is known by id only
+				if s.pruned(e) {
+					return NotFoundEvent(e)
+				}
+			}
+
+			return nil
+		},
+
+		Check: func(e dag.Event, parents dag.Events) error {
+			if !config.Verify {
+				return nil
+			}
+			info := s.events.info[e.ID()]
+			// placeholders have no parents to check
+			if info == nil || internal.IsPlaceholder(info.Role) {
+				return nil
+			}
+			if failed := verifyParents(e, parents); len(failed) > 0 {
+				s.Log.Warn("event doesn't match its parents", "id", e.ID())
+				info.Integrity = joinIntegrity(info.Integrity, failed)
+			}
+			// flagged events are not dropped
+			return nil
+		},
+	})
+
+	return s
+}
+
+func (s *EventsBuffer) exists(e hash.Event) bool {
+	if info, ok := s.events.info[e]; ok && info.Update {
+		// replaces stored version
+		return false
+	}
+
+	ee, ok := s.events.processed[e.Epoch()]
+	if ok {
+		if _, exists := ee[e]; exists {
+			return true
+		}
+	}
+
+	// older epochs are possible for parents of the recovered events
+	if !ok || len(s.events.processed) < 2 {
+		stored, err := s.db.HasEvent(e)
+		if err != nil {
+			s.storageFailure(err)
+		}
+		return stored || s.pruned(e)
+	}
+
+	return false
+}
+
+// pruned returns true if the event is pruned from db, so it is never written again.
+func (s *EventsBuffer) pruned(e hash.Event) bool {
+	if time.Since(s.cutoff.at) > cutoffTTL {
+		cutoff, err := pruneCutoff(s.db)
+		if err != nil {
+			s.storageFailure(err)
+			return false
+		}
+		s.cutoff.PruneCutoff, s.cutoff.at = cutoff, time.Now()
+	}
+	return s.cutoff.Pruned(e)
+}
+
+// storageFailure stops buffer, the event is considered as not stored yet.
+func (s *EventsBuffer) storageFailure(err error) {
+	if s.Err() == nil {
+		s.Log.Error("storage failure", "err", err)
+	}
+	s.fail(err)
+}
+
+func (s *EventsBuffer) forget(id hash.Event) {
+	delete(s.events.info, id)
+	delete(s.events.since, id)
+}
+
+func (s *EventsBuffer) Push(e *internal.EventInfo) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.push(e)
+}
+
+func (s *EventsBuffer) push(e *internal.EventInfo) {
+	id := e.Event.ID()
+	if replaced, ok := s.events.info[id]; ok {
+		s.release(replaced.Block)
+	}
+	s.hold(e.Block)
+	s.events.info[id] = e
+	if _, ok := s.events.since[id]; !ok {
+		s.events.since[id] = time.Now()
+	}
+	s.ordering.PushEvent(e.Event, "")
+
+	bufferBacklogGauge().Update(int64(len(s.events.info)))
+	bufferIncompleteGauge().Update(int64(s.ordering.Total().Num))
+}
+
+func (s *EventsBuffer) hold(block idx.Block) {
+	if s.holder != nil {
+		s.holder.hold(block)
+	}
+}
+
+func (s *EventsBuffer) release(block idx.Block) {
+	if s.holder != nil {
+		s.holder.release(block)
+	}
+}
+
+// Incomplete returns the events which wait for their parents.
+func (s *EventsBuffer) Incomplete() []*internal.EventInfo {
+	s.RLock()
+	defer s.RUnlock()
+
+	var incomplete []*internal.EventInfo
+	for id, info := range s.events.info {
+		if s.ordering.IsBuffered(id) {
+			incomplete = append(incomplete, info)
+		}
+	}
+	return incomplete
+}
+
+// CheckGaps finds missing parents of the events which are incomplete longer than timeout.
+// The missing parents are re-requested first (with the child block),
+// and replaced with gap markers if they are still missing after one more timeout.
+func (s *EventsBuffer) CheckGaps(request func(map[hash.Event]idx.Block)) {
+	if s.config.GapTimeout <= 0 {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	var (
+		now     = time.Now()
+		missing = make(map[hash.Event]idx.Block)
+		waiting int
+	)
+	for id, since := range s.events.since {
+		if now.Sub(since) < s.config.GapTimeout || !s.ordering.IsBuffered(id) {
+			continue
+		}
+		waiting++
+		info := s.events.info[id]
+		for _, p := range info.Event.Parents() {
+			if _, known := s.events.info[p]; known || s.exists(p) {
+				continue
+			}
+			missing[p] = info.Block
+		}
+	}
+	if len(missing) < 1 {
+		return
+	}
+	s.Log.Warn("incomplete events wait for missing parents", "incomplete", waiting, "missing", len(missing))
+
+	var (
+		requests = make(map[hash.Event]idx.Block)
+		gaps     = make(map[hash.Event]idx.Block)
+	)
+	for p, block := range missing {
+		at, requested := s.events.requested[p]
+		switch {
+		case !requested:
+			s.events.requested[p] = now
+			requests[p] = block
+		case now.Sub(at) >= s.config.GapTimeout:
+			gaps[p] = block
+		}
+	}
+
+	if len(requests) > 0 {
+		request(requests)
+	}
+	for p, block := range gaps {
+		s.Log.Warn("fill gap of missing event", "id", p, "block", block)
+		bufferGapsCounter().Inc(1)
+		delete(s.events.requested, p)
+		s.push(&internal.EventInfo{
+			Block: block,
+			Event: NotFoundEvent(p),
+			Role:  internal.GapRole,
+		})
+	}
+}
+
+// Close drops the incomplete events and waits until the completed ones are written.
+// It returns count of the dropped events, which are not written. Checkpoint is moved
+// before the earliest block of them, so they are read again on the next run.
+func (s *EventsBuffer) Close() (dropped int, err error) {
+	s.Lock()
+	s.ordering.Clear()
+	close(s.output)
+	dropped = len(s.events.dropped)
+	earliest := internal.UnconfirmedBlock
+	for _, block := range s.events.dropped {
+		if block != internal.UnconfirmedBlock && (earliest == internal.UnconfirmedBlock || block < earliest) {
+			earliest = block
+		}
+	}
+	s.Unlock()
+
+	s.busy.Wait()
+
+	if earliest == internal.UnconfirmedBlock {
+		return
+	}
+	last, err := s.db.GetLastBlock()
+	if err != nil || last < earliest {
+		return
+	}
+	s.Log.Warn("checkpoint is moved back to dropped events", "block", earliest-1)
+	err = s.db.SetLastBlock(earliest - 1)
+	return
+}
@@ -0,0 +1,158 @@
+package reader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+func TestBufferGaps(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 5)
+	db := internal.NewMemDb()
+
+	config := DefaultBufferConfig()
+	config.GapTimeout = 50 * time.Millisecond
+	buffer := NewEventsBuffer(db, config)
+	defer buffer.Close()
+
+	missing := node.firstEvent()
+	for id, e := range node.events {
+		if id == missing {
+			continue
+		}
+		buffer.Push(&internal.EventInfo{
+			Block: 1,
+			Event: e,
+		})
+	}
+	require.NotEmpty(buffer.Incomplete())
+
+	var requested map[hash.Event]idx.Block
+	request := func(missing map[hash.Event]idx.Block) {
+		requested = missing
+	}
+
+	buffer.CheckGaps(request)
+	require.Nil(requested, "too early")
+
+	time.Sleep(config.GapTimeout)
+	buffer.CheckGaps(request)
+	require.Contains(requested, missing)
+	require.False(hasEvent(t, db, missing))
+
+	requested = nil
+	time.Sleep(config.GapTimeout)
+	buffer.CheckGaps(request)
+	require.Nil(requested, "requested once")
+
+	require.Eventually(func() bool {
+		return len(buffer.Incomplete()) == 0 && len(getPlaceholders(t, db)) == 1
+	}, time.Second, 10*time.Millisecond)
+	gap := getPlaceholders(t, db)[0]
+	require.Equal(missing, gap.Event.ID())
+	require.Equal(internal.GapRole, gap.Role)
+	for id := range node.events {
+		require.True(hasEvent(t, db, id), id.String())
+	}
+}
+
+func TestBufferClose(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 6)
+	db := internal.NewMemDb()
+	require.NoError(db.SetLastBlock(5))
+
+	fanout, err := NewFanout(db)
+	require.NoError(err)
+	buffer := NewEventsBuffer(fanout, DefaultBufferConfig())
+
+	missing := node.firstEvent()
+	for id, e := range node.events {
+		if id == missing {
+			continue
+		}
+		buffer.Push(&internal.EventInfo{
+			Block: 3,
+			Event: e,
+		})
+	}
+	incomplete := buffer.Incomplete()
+	require.NotEmpty(incomplete)
+
+	dropped, err := buffer.Close()
+	require.NoError(err)
+	require.Equal(len(incomplete), dropped)
+	require.Equal(idx.Block(2), lastBlock(t, db))
+	for id := range node.events {
+		require.Equal(!containsEvent(incomplete, id) && id != missing, hasEvent(t, db, id), id.String())
+	}
+}
+
+func TestBufferCheckpoint(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 7)
+	db := internal.NewMemDb()
+
+	fanout, err := NewFanout(db)
+	require.NoError(err)
+	buffer := NewEventsBuffer(fanout, DefaultBufferConfig())
+	defer buffer.Close()
+
+	missing := node.firstEvent()
+	for id, e := range node.events {
+		if id == missing {
+			continue
+		}
+		buffer.Push(&internal.EventInfo{
+			Block: 5,
+			Event: e,
+		})
+	}
+	incomplete := buffer.Incomplete()
+	require.NotEmpty(incomplete)
+	require.Eventually(func() bool {
+		for id := range node.events {
+			if id != missing && !containsEvent(incomplete, id) && !hasEvent(t, db, id) {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(idx.Block(4), lastBlock(t, db), "block of the incomplete events is not passed")
+
+	buffer.Push(&internal.EventInfo{
+		Block: 5,
+		Event: node.events[missing],
+	})
+	require.Eventually(func() bool {
+		return lastBlock(t, db) == 5
+	}, time.Second, 10*time.Millisecond)
+}
+
+// firstEvent returns the DAG event with no parents and the lowest lamport.
+func (n *fakeNode) firstEvent() (first hash.Event) {
+	for id, e := range n.events {
+		if len(e.Parents()) == 0 && (first == hash.ZeroEvent || id.Lamport() < first.Lamport()) {
+			first = id
+		}
+	}
+	return
+}
+
+func containsEvent(ee []*internal.EventInfo, id hash.Event) bool {
+	for _, info := range ee {
+		if info.Event.ID() == id {
+			return true
+		}
+	}
+	return false
+}
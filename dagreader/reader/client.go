@@ -0,0 +1,180 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/Fantom-foundation/go-opera/ftmclient"
+	"github.com/Fantom-foundation/go-opera/inter"
+	"github.com/Fantom-foundation/go-opera/inter/validatorpk"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// Client is a subset of the opera node API which DagReader uses.
+type Client interface {
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	GetEvent(ctx context.Context, h hash.Event) (dag.Event, error)
+	GetHeads(ctx context.Context, epoch *big.Int) (hash.Events, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+	Close()
+}
+
+// ValidatorsClient serves the epoch validators, epoch infos are recorded with them.
+type ValidatorsClient interface {
+	Client
+	GetValidators(ctx context.Context, epoch idx.Epoch) ([]*internal.EpochValidator, error)
+}
+
+// Dialer connects to the DAG source.
+type Dialer func(url string) (Client, error)
+
+// Source is where DagReader reads DAG from.
+type Source struct {
+	URL  string
+	Dial Dialer
+	// Finite source has no new blocks, so reader stops after the last one.
+	Finite bool
+}
+
+// RPCSource is the opera node API.
+func RPCSource(url string) Source {
+	return Source{
+		URL:  url,
+		Dial: DialRPC,
+	}
+}
+
+// DatadirSource is the datadir of a stopped opera node.
+func DatadirSource(datadir string) Source {
+	return Source{
+		URL:    datadir,
+		Dial:   dialDatadir,
+		Finite: true,
+	}
+}
+
+// rpcClient adapts ftmclient to Client and PayloadClient interfaces.
+type rpcClient struct {
+	*ftmclient.Client
+	rpc *rpc.Client
+}
+
+// DialRPC connects to the opera node API.
+func DialRPC(url string) (Client, error) {
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return rpcClient{ftmclient.NewClient(client), client}, nil
+}
+
+func (c rpcClient) GetEvent(ctx context.Context, h hash.Event) (dag.Event, error) {
+	e, err := c.Client.GetEvent(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// GetEventPayload gets the event transactions by their hashes. API serves no signature,
+// misbehaviour proofs and votes, so payload of the events which have them is not verified.
+func (c rpcClient) GetEventPayload(ctx context.Context, h hash.Event) (*EventPayload, error) {
+	e, hashes, err := c.Client.GetEventPayload(ctx, h, true)
+	if err != nil {
+		return nil, err
+	}
+	res := &EventPayload{
+		Event: e,
+	}
+	if e.AnyMisbehaviourProofs() || e.AnyEpochVote() || e.AnyBlockVotes() {
+		return res, nil
+	}
+
+	txs := make(types.Transactions, len(hashes))
+	batch := make([]rpc.BatchElem, len(hashes))
+	for i, tx := range hashes {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getTransactionByHash",
+			Args:   []interface{}{tx},
+			Result: &txs[i],
+		}
+	}
+	if len(batch) > 0 {
+		if err = c.rpc.BatchCallContext(ctx, batch); err != nil {
+			return nil, err
+		}
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, elem.Error
+		}
+		if txs[i] == nil {
+			return nil, fmt.Errorf("transaction %s of event %s: %w", hashes[i].Hex(), h.String(), ethereum.NotFound)
+		}
+	}
+
+	payload := &inter.MutableEventPayload{}
+	payload.SetVersion(e.Version())
+	payload.SetTxs(txs)
+	res.Payload = payload
+	return res, nil
+}
+
+// GetValidatorKeys gets the epoch validators with abft_getValidators.
+func (c rpcClient) GetValidatorKeys(ctx context.Context, epoch idx.Epoch) (map[idx.ValidatorID]validatorpk.PubKey, error) {
+	var raw map[hexutil.Uint64]struct {
+		PubKey string `json:"pubkey"`
+	}
+	err := c.rpc.CallContext(ctx, &raw, "abft_getValidators", hexutil.Uint64(epoch))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, ethereum.NotFound
+	}
+
+	keys := make(map[idx.ValidatorID]validatorpk.PubKey, len(raw))
+	for id, v := range raw {
+		pubkey, err := validatorpk.FromString(v.PubKey)
+		if err != nil {
+			return nil, fmt.Errorf("validator %d: %w", id, err)
+		}
+		keys[idx.ValidatorID(id)] = pubkey
+	}
+	return keys, nil
+}
+
+// GetValidators gets the epoch validators with their weights with abft_getValidators.
+func (c rpcClient) GetValidators(ctx context.Context, epoch idx.Epoch) ([]*internal.EpochValidator, error) {
+	var raw map[hexutil.Uint64]struct {
+		Weight *hexutil.Big `json:"weight"`
+		PubKey string       `json:"pubkey"`
+	}
+	err := c.rpc.CallContext(ctx, &raw, "abft_getValidators", hexutil.Uint64(epoch))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, ethereum.NotFound
+	}
+
+	validators := make([]*internal.EpochValidator, 0, len(raw))
+	for id, v := range raw {
+		validators = append(validators, &internal.EpochValidator{
+			ID:     idx.ValidatorID(id),
+			Weight: v.Weight.ToInt(),
+			PubKey: v.PubKey,
+		})
+	}
+	internal.SortValidators(validators)
+	return validators, nil
+}
@@ -0,0 +1,126 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// errNoDag means the node has no matching blocks with DAG yet.
+var errNoDag = errors.New("no blocks with DAG yet")
+
+// DetectDagStart finds the first block whose atropos is served by the node.
+// The genesis blocks have no DAG and the next ones all have it.
+func DetectDagStart(client Client) (idx.Block, error) {
+	return firstBlock(client, func(atropos hash.Event) bool {
+		return true
+	})
+}
+
+// firstBlock finds the first block whose atropos is served and matches,
+// the matching blocks must follow the not matching ones. It is a binary search
+// between the last not matching block and the first matching block (or after the last block)
+// found by doubling the block number.
+func firstBlock(client Client, match func(atropos hash.Event) bool) (idx.Block, error) {
+	// found is true for the matching blocks and for the blocks after the last one
+	found := func(n idx.Block) (ok bool, exists bool, err error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+		defer cancel()
+
+		blk, err := client.BlockByNumber(ctx, big.NewInt(int64(n)))
+		if notFound(err) {
+			return true, false, nil
+		}
+		if err != nil {
+			rpcErrorsCounter("BlockByNumber").Inc(1)
+			return false, false, fmt.Errorf("block %d: %w", n, err)
+		}
+
+		atropos := hash.Event(blk.Hash())
+		_, err = client.GetEvent(ctx, atropos)
+		if notFound(err) {
+			return false, true, nil
+		}
+		if err != nil {
+			rpcErrorsCounter("GetEvent").Inc(1)
+			return false, true, fmt.Errorf("atropos of block %d: %w", n, err)
+		}
+		return match(atropos), true, nil
+	}
+
+	var lo, hi idx.Block = 0, 1
+	for {
+		ok, _, err := found(hi)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			break
+		}
+		lo, hi = hi, hi*2
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		ok, _, err := found(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	_, exists, err := found(hi)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, errNoDag
+	}
+	return hi, nil
+}
+
+// dagStart returns the cached first block with DAG, 0 if it is not detected yet.
+func (r *DagReader) dagStart() (idx.Block, error) {
+	state, ok := r.storage.(internal.DagStartState)
+	if !ok {
+		return AutoDagStart, nil
+	}
+	n, err := state.GetDagStart()
+	if err != nil {
+		return 0, storageError{err}
+	}
+	return n, nil
+}
+
+// detectDagStart detects the first block with DAG and caches it.
+func (r *DagReader) detectDagStart(client Client) (idx.Block, error) {
+	n, err := DetectDagStart(client)
+	if err != nil {
+		r.Log.Error("detect first DAG block", "err", err)
+		return 0, err
+	}
+	r.Log.Info("detected first DAG block", "dagstart", n)
+
+	if state, ok := r.storage.(internal.DagStartState); ok {
+		err = state.SetDagStart(n)
+		if err != nil {
+			return 0, storageError{err}
+		}
+	}
+	return n, nil
+}
+
+func notFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "not found")
+}
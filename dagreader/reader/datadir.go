@@ -0,0 +1,193 @@
+//go:build datadir
+// +build datadir
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"path"
+
+	"github.com/Fantom-foundation/go-opera/gossip"
+	"github.com/Fantom-foundation/go-opera/inter"
+	"github.com/Fantom-foundation/go-opera/inter/validatorpk"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/Fantom-foundation/lachesis-base/utils/cachescale"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// datadirClient serves Client API from the gossip store of a stopped opera node.
+// The store databases are opened read-only and the store is not migrated.
+type datadirClient struct {
+	store *gossip.Store
+	last  idx.Block
+}
+
+// dialDatadir opens the gossip store of the opera datadir, the store version must be
+// of the go-opera dependency, as the store of other versions is migrated on open.
+func dialDatadir(datadir string) (Client, error) {
+	producer := &readonlyProducer{dir: path.Join(datadir, "chaindata")}
+	db, err := producer.OpenDB("gossip")
+	if err != nil {
+		return nil, fmt.Errorf("datadir is not initialized: %s: %w", datadir, err)
+	}
+	version := storeVersion(db)
+	db.Close()
+	if latest := latestStoreVersion(); version != latest {
+		return nil, fmt.Errorf("datadir store version %q is not %q, open it by the matching go-opera first", version, latest)
+	}
+
+	store := gossip.NewStore(producer, gossip.DefaultStoreConfig(cachescale.Identity))
+	if store.GetGenesisID() == nil {
+		store.Close()
+		return nil, errors.New("datadir has no genesis: " + datadir)
+	}
+
+	return newDatadirClient(store, store.GetLatestBlockIndex()), nil
+}
+
+func newDatadirClient(store *gossip.Store, last idx.Block) *datadirClient {
+	return &datadirClient{
+		store: store,
+		last:  last,
+	}
+}
+
+// BlockByNumber returns block header only, the header hash is the block atropos.
+func (c *datadirClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	n := idx.Block(number.Uint64())
+	if n > c.last {
+		return nil, ethereum.NotFound
+	}
+	b := c.store.GetBlock(n)
+	if b == nil {
+		return nil, ethereum.NotFound
+	}
+
+	header := &types.Header{
+		Number: new(big.Int).Set(number),
+		Time:   uint64(b.Time.Unix()),
+	}
+	header.SetExternalHash(common.Hash(b.Atropos))
+
+	return types.NewBlockWithHeader(header), nil
+}
+
+func (c *datadirClient) GetEvent(ctx context.Context, h hash.Event) (dag.Event, error) {
+	e := c.store.GetEvent(h)
+	if e == nil {
+		return nil, ethereum.NotFound
+	}
+	return e, nil
+}
+
+// GetEventPayload returns the stored event with its full payload and signature.
+func (c *datadirClient) GetEventPayload(ctx context.Context, h hash.Event) (*EventPayload, error) {
+	e := c.store.GetEventPayload(h)
+	if e == nil {
+		return nil, ethereum.NotFound
+	}
+	sig := e.Sig()
+	return &EventPayload{
+		Event:   e,
+		Payload: e,
+		Sig:     &sig,
+	}, nil
+}
+
+// GetValidatorKeys returns keys of the epoch validators from the epoch state history.
+func (c *datadirClient) GetValidatorKeys(ctx context.Context, epoch idx.Epoch) (map[idx.ValidatorID]validatorpk.PubKey, error) {
+	es := c.store.GetHistoryEpochState(epoch)
+	if es == nil {
+		return nil, ethereum.NotFound
+	}
+	keys := make(map[idx.ValidatorID]validatorpk.PubKey, len(es.ValidatorProfiles))
+	for id, profile := range es.ValidatorProfiles {
+		keys[id] = profile.PubKey
+	}
+	return keys, nil
+}
+
+// GetHeads returns the events of the epoch which are not parents of the others,
+// -1 is for the current epoch, nil is for the latest sealed epoch (as go-opera API).
+// The stopped node keeps the events of the sealed epochs, so their heads are served also.
+func (c *datadirClient) GetHeads(ctx context.Context, epoch *big.Int) (hash.Events, error) {
+	requested := c.store.GetEpoch()
+	switch {
+	case epoch == nil:
+		requested--
+	case epoch.Sign() < 0:
+	case epoch.Uint64() <= uint64(requested):
+		requested = idx.Epoch(epoch.Uint64())
+	default:
+		return nil, ethereum.NotFound
+	}
+
+	var (
+		events  = hash.EventsSet{}
+		parents = hash.EventsSet{}
+	)
+	c.store.ForEachEpochEvent(requested, func(e *inter.EventPayload) bool {
+		events.Add(e.ID())
+		parents.Add(e.Parents()...)
+		return ctx.Err() == nil
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	heads := make(hash.Events, 0, len(events))
+	for id := range events {
+		if !parents.Contains(id) {
+			heads = append(heads, id)
+		}
+	}
+	return heads, nil
+}
+
+// GetValidators returns the epoch validators from the epoch state.
+func (c *datadirClient) GetValidators(ctx context.Context, epoch idx.Epoch) ([]*internal.EpochValidator, error) {
+	es := c.store.GetHistoryEpochState(epoch)
+	if es == nil {
+		return nil, ethereum.NotFound
+	}
+	validators := make([]*internal.EpochValidator, 0, len(es.ValidatorProfiles))
+	for id, profile := range es.ValidatorProfiles {
+		validators = append(validators, &internal.EpochValidator{
+			ID:     id,
+			Weight: new(big.Int).Set(profile.Weight),
+			PubKey: profile.PubKey.String(),
+		})
+	}
+	internal.SortValidators(validators)
+	return validators, nil
+}
+
+// SubscribeNewHead sends the last block header once, the stopped node has no new blocks.
+func (c *datadirClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	header := &types.Header{
+		Number: big.NewInt(int64(c.last)),
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		select {
+		case ch <- header:
+		case <-quit:
+			return nil
+		}
+		<-quit
+		return nil
+	}), nil
+}
+
+func (c *datadirClient) Close() {
+	c.store.Close()
+}
@@ -0,0 +1,171 @@
+//go:build datadir
+// +build datadir
+
+package reader
+
+import (
+	"errors"
+	"path"
+
+	"github.com/Fantom-foundation/go-opera/gossip"
+	"github.com/Fantom-foundation/go-opera/integration"
+	"github.com/Fantom-foundation/go-opera/utils/migration"
+	"github.com/Fantom-foundation/lachesis-base/kvdb"
+	"github.com/Fantom-foundation/lachesis-base/kvdb/memorydb"
+	"github.com/Fantom-foundation/lachesis-base/kvdb/table"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var errReadOnly = errors.New("datadir is opened read-only")
+
+// readonlyProducer opens the datadir databases read-only, they are never flushed.
+type readonlyProducer struct {
+	dir string
+}
+
+func (p *readonlyProducer) OpenDB(name string) (kvdb.DropableStore, error) {
+	db, err := leveldb.OpenFile(path.Join(p.dir, name), &opt.Options{
+		ReadOnly:       true,
+		ErrorIfMissing: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &readonlyDb{db}, nil
+}
+
+func (p *readonlyProducer) NotFlushedSizeEst() int {
+	return 0
+}
+
+func (p *readonlyProducer) Flush(id []byte) error {
+	return nil
+}
+
+// storeVersion returns the id of the last migration applied to the gossip db.
+func storeVersion(db kvdb.Store) string {
+	return migration.NewKvdbIDStore(table.New(db, []byte("_"))).GetID()
+}
+
+// latestStoreVersion returns the id of the last migration of the go-opera gossip store,
+// the store of the other version is migrated on open.
+func latestStoreVersion() string {
+	mems := memorydb.NewProducer("")
+	store := gossip.NewStore(&integration.DummyFlushableProducer{DBProducer: mems}, gossip.LiteStoreConfig())
+	defer store.Close()
+
+	db, _ := mems.OpenDB("gossip")
+	return storeVersion(db)
+}
+
+// readonlyDb is a kvdb.Store over the read-only leveldb, all the writes fail.
+type readonlyDb struct {
+	db *leveldb.DB
+}
+
+func (r *readonlyDb) Has(key []byte) (bool, error) {
+	return r.db.Has(key, nil)
+}
+
+func (r *readonlyDb) Get(key []byte) ([]byte, error) {
+	val, err := r.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	return val, err
+}
+
+func (r *readonlyDb) NewIterator(prefix []byte, start []byte) kvdb.Iterator {
+	return r.db.NewIterator(prefixRange(prefix, start), nil)
+}
+
+func (r *readonlyDb) GetSnapshot() (kvdb.Snapshot, error) {
+	snap, err := r.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &readonlySnapshot{snap}, nil
+}
+
+func (r *readonlyDb) Stat(property string) (string, error) {
+	return r.db.GetProperty(property)
+}
+
+func (r *readonlyDb) Put(key []byte, value []byte) error {
+	return errReadOnly
+}
+
+func (r *readonlyDb) Delete(key []byte) error {
+	return errReadOnly
+}
+
+func (r *readonlyDb) NewBatch() kvdb.Batch {
+	return readonlyBatch{}
+}
+
+func (r *readonlyDb) Compact(start []byte, limit []byte) error {
+	return errReadOnly
+}
+
+func (r *readonlyDb) Close() error {
+	return r.db.Close()
+}
+
+func (r *readonlyDb) Drop() {}
+
+type readonlySnapshot struct {
+	snap *leveldb.Snapshot
+}
+
+func (s *readonlySnapshot) Has(key []byte) (bool, error) {
+	return s.snap.Has(key, nil)
+}
+
+func (s *readonlySnapshot) Get(key []byte) ([]byte, error) {
+	val, err := s.snap.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	return val, err
+}
+
+func (s *readonlySnapshot) NewIterator(prefix []byte, start []byte) kvdb.Iterator {
+	return s.snap.NewIterator(prefixRange(prefix, start), nil)
+}
+
+func (s *readonlySnapshot) Release() {
+	s.snap.Release()
+}
+
+// readonlyBatch fails to write.
+type readonlyBatch struct{}
+
+func (readonlyBatch) Put(key []byte, value []byte) error {
+	return errReadOnly
+}
+
+func (readonlyBatch) Delete(key []byte) error {
+	return errReadOnly
+}
+
+func (readonlyBatch) ValueSize() int {
+	return 0
+}
+
+func (readonlyBatch) Write() error {
+	return errReadOnly
+}
+
+func (readonlyBatch) Reset() {}
+
+func (readonlyBatch) Replay(w kvdb.Writer) error {
+	return nil
+}
+
+func prefixRange(prefix, start []byte) *util.Range {
+	r := util.BytesPrefix(prefix)
+	r.Start = append(r.Start, start...)
+	return r
+}
@@ -0,0 +1,13 @@
+//go:build !datadir
+// +build !datadir
+
+package reader
+
+import (
+	"errors"
+)
+
+// dialDatadir is not supported, see datadir.go.
+func dialDatadir(datadir string) (Client, error) {
+	return nil, errors.New("datadir import is not supported by this build, rebuild with '-tags datadir'")
+}
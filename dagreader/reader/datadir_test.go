@@ -0,0 +1,141 @@
+//go:build datadir
+// +build datadir
+
+package reader
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/go-opera/gossip"
+	"github.com/Fantom-foundation/go-opera/integration"
+	"github.com/Fantom-foundation/go-opera/inter"
+	"github.com/Fantom-foundation/go-opera/inter/iblockproc"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/Fantom-foundation/lachesis-base/inter/pos"
+	"github.com/Fantom-foundation/lachesis-base/utils/cachescale"
+	"github.com/ethereum/go-ethereum"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// makeDatadir writes a stopped node datadir by go-opera store: epoch 1 event,
+// block 1 of epoch 2 and a tail event of epoch 2 which is not confirmed.
+func makeDatadir(t *testing.T) (datadir string, events []*inter.EventPayload) {
+	datadir = t.TempDir()
+	producer := integration.DBProducer(filepath.Join(datadir, "chaindata"), cachescale.Identity)
+	store := gossip.NewStore(&integration.DummyFlushableProducer{DBProducer: producer}, gossip.LiteStoreConfig())
+	defer store.Close()
+
+	event := func(epoch idx.Epoch, creator idx.ValidatorID, lamport idx.Lamport, parents ...hash.Event) *inter.EventPayload {
+		me := &inter.MutableEventPayload{}
+		me.SetVersion(1)
+		me.SetEpoch(epoch)
+		me.SetCreator(creator)
+		me.SetLamport(lamport)
+		me.SetSeq(idx.Event(lamport))
+		me.SetParents(parents)
+		me.SetPayloadHash(inter.CalcPayloadHash(me))
+		e := me.Build()
+		store.SetEvent(e)
+		events = append(events, e)
+		return e
+	}
+	event(1, 1, 1)
+	a := event(2, 1, 1)
+	b := event(2, 2, 1)
+	atropos := event(2, 1, 2, a.ID(), b.ID())
+	event(2, 2, 3, atropos.ID())
+
+	store.SetGenesisID(hash.Hash{1})
+	store.SetBlock(1, &inter.Block{Atropos: atropos.ID()})
+	bs := iblockproc.BlockState{
+		LastBlock: iblockproc.BlockCtx{Idx: 1, Atropos: atropos.ID()},
+	}
+	es := iblockproc.EpochState{
+		Epoch:      2,
+		Validators: pos.EqualWeightValidators([]idx.ValidatorID{1, 2}, 1),
+		ValidatorProfiles: iblockproc.ValidatorProfiles{
+			1: {Weight: big.NewInt(1)},
+			2: {Weight: big.NewInt(1)},
+		},
+	}
+	store.SetBlockEpochState(bs, es)
+	store.FlushBlockEpochState()
+	store.SetHistoryBlockEpochState(2, bs, es)
+
+	return datadir, events
+}
+
+// dirSum is a checksum of the dir files names and contents.
+func dirSum(t *testing.T, dir string) [32]byte {
+	h := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(path))
+		h.Write(data)
+		return nil
+	})
+	require.NoError(t, err)
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func TestDatadirClient(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	datadir, events := makeDatadir(t)
+	old, atropos, tail := events[0], events[3], events[4]
+	before := dirSum(t, datadir)
+
+	client, err := dialDatadir(datadir)
+	require.NoError(err)
+
+	blk, err := client.BlockByNumber(ctx, big.NewInt(1))
+	require.NoError(err)
+	require.Equal(atropos.ID(), hash.Event(blk.Hash()))
+	_, err = client.BlockByNumber(ctx, big.NewInt(2))
+	require.ErrorIs(err, ethereum.NotFound)
+
+	for _, e := range events {
+		got, err := client.GetEvent(ctx, e.ID())
+		require.NoError(err)
+		require.Equal(e.ID(), got.ID())
+	}
+
+	heads, err := client.GetHeads(ctx, big.NewInt(-1))
+	require.NoError(err)
+	require.Equal(hash.Events{tail.ID()}, heads)
+	heads, err = client.GetHeads(ctx, nil)
+	require.NoError(err)
+	require.Equal(hash.Events{old.ID()}, heads)
+
+	validators, err := client.(ValidatorsClient).GetValidators(ctx, 2)
+	require.NoError(err)
+	require.Len(validators, 2)
+
+	client.Close()
+	require.Equal(before, dirSum(t, datadir), "datadir is changed")
+
+	// the store of other version is not migrated
+	db, err := leveldb.OpenFile(filepath.Join(datadir, "chaindata", "gossip"), nil)
+	require.NoError(err)
+	require.NoError(db.Put([]byte("_id"), []byte("old"), nil))
+	require.NoError(db.Close())
+	_, err = dialDatadir(datadir)
+	require.Error(err)
+	require.Contains(err.Error(), "store version")
+}
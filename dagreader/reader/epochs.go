@@ -0,0 +1,181 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+type (
+	// EpochInfo is the epoch boundaries and validators.
+	EpochInfo = internal.EpochInfo
+	// EpochValidator is a validator of the epoch.
+	EpochValidator = internal.EpochValidator
+	// EpochStore is a Db which stores the epoch infos.
+	EpochStore = internal.EpochStore
+)
+
+// EpochRange is the epochs From..To (inclusive), zero range is all the epochs.
+type EpochRange struct {
+	From, To idx.Epoch
+}
+
+// ParseEpochRange parses "a..b" or "a" (the only epoch).
+func ParseEpochRange(s string) (EpochRange, error) {
+	parse := func(v string) (idx.Epoch, error) {
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 32)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid epoch %q in range %q", v, s)
+		}
+		return idx.Epoch(n), nil
+	}
+
+	var (
+		r   EpochRange
+		err error
+	)
+	bounds := strings.SplitN(s, "..", 2)
+	r.From, err = parse(bounds[0])
+	if err != nil {
+		return r, err
+	}
+	r.To = r.From
+	if len(bounds) > 1 {
+		r.To, err = parse(bounds[1])
+		if err != nil {
+			return r, err
+		}
+	}
+	if r.To < r.From {
+		return r, fmt.Errorf("invalid epoch range %q, %d is after %d", s, r.From, r.To)
+	}
+	return r, nil
+}
+
+// IsZero returns true for all the epochs.
+func (r EpochRange) IsZero() bool {
+	return r.From == 0 && r.To == 0
+}
+
+// Contains returns true if the epoch is in the range.
+func (r EpochRange) Contains(epoch idx.Epoch) bool {
+	return r.IsZero() || r.From <= epoch && epoch <= r.To
+}
+
+func (r EpochRange) String() string {
+	return fmt.Sprintf("%d..%d", r.From, r.To)
+}
+
+// rangeDb keeps the checkpoint, as it is of the continuous reading but epochs mode reads a range.
+type rangeDb struct {
+	internal.Db
+}
+
+func (rangeDb) SetLastBlock(idx.Block) error {
+	return nil
+}
+
+func (r rangeDb) GetPruneCutoff() (internal.PruneCutoff, error) {
+	return pruneCutoff(r.Db)
+}
+
+// firstEpochBlock finds the first block of the first epoch of the range.
+func (s *DagReader) firstEpochBlock(client Client) (idx.Block, error) {
+	n, err := firstBlock(client, func(atropos hash.Event) bool {
+		return atropos.Epoch() >= s.epochs.From
+	})
+	if err != nil {
+		s.Log.Error("find first block of epoch", "epoch", s.epochs.From, "err", err)
+		return 0, err
+	}
+	s.Log.Info("found first block of epoch", "epoch", s.epochs.From, "block", n)
+	return n, nil
+}
+
+// epochBlock tracks the epoch of the block atropos, the previous epoch is finished when the block is of the next one.
+// It returns false if the block is after the range.
+func (s *DagReader) epochBlock(client Client, n idx.Block, atropos hash.Event) (bool, error) {
+	if s.epoch != nil && s.epoch.Epoch != atropos.Epoch() {
+		s.epoch.SealingBlock = n - 1
+		err := s.finishEpoch(client)
+		if err != nil {
+			return false, err
+		}
+		s.epoch = nil
+	}
+	if !s.epochs.Contains(atropos.Epoch()) {
+		return false, nil
+	}
+	if s.epoch == nil {
+		s.epoch = &internal.EpochInfo{
+			Epoch:      atropos.Epoch(),
+			FirstBlock: n,
+		}
+		s.Log.Info("read epoch", "epoch", atropos.Epoch(), "first", n)
+	}
+	return true, nil
+}
+
+// finishEpoch stores the epoch info with its validators. The events of the epoch
+// which are not confirmed by any block are read by the heads polling before.
+func (s *DagReader) finishEpoch(client Client) error {
+	info := s.epoch
+	if !info.Tail {
+		s.Log.Warn("epoch tail events are not read, node serves them while the epoch is current only", "epoch", info.Epoch)
+	}
+
+	validators, err := s.getValidators(client, info.Epoch)
+	if err != nil {
+		return err
+	}
+	info.Validators = validators
+
+	if store, ok := s.storage.(internal.EpochStore); ok {
+		err := store.SetEpochInfo(info)
+		if err != nil {
+			return storageError{err}
+		}
+	}
+	s.Log.Info("epoch is read", "epoch", info.Epoch, "first", info.FirstBlock, "sealing", info.SealingBlock,
+		"validators", len(info.Validators), "tail", info.Tail)
+	return nil
+}
+
+// getValidators returns the epoch validators, nil if the source doesn't serve them.
+func (s *DagReader) getValidators(client Client, epoch idx.Epoch) ([]*internal.EpochValidator, error) {
+	vc, ok := client.(ValidatorsClient)
+	if !ok {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	validators, err := vc.GetValidators(ctx, epoch)
+	cancel()
+	if err != nil && !notFound(err) {
+		rpcErrorsCounter("GetValidators").Inc(1)
+		s.Log.Error("get validators", "epoch", epoch, "err", err)
+		return nil, err
+	}
+	return validators, nil
+}
+
+// passValidators passes the validators of the next read epoch to onValidators,
+// before the epoch events are pushed.
+func (s *DagReader) passValidators(client Client, epoch idx.Epoch) error {
+	if s.onValidators == nil || epoch <= s.validatorsEpoch || !s.epochs.Contains(epoch) {
+		return nil
+	}
+	validators, err := s.getValidators(client, epoch)
+	if err != nil {
+		return err
+	}
+	s.onValidators(epoch, validators)
+	s.validatorsEpoch = epoch
+	return nil
+}
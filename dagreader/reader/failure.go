@@ -0,0 +1,40 @@
+package reader
+
+import (
+	"sync"
+)
+
+// failure keeps the first fatal error, which stops the component.
+type failure struct {
+	err    error
+	failed chan struct{}
+	once   sync.Once
+}
+
+func newFailure() failure {
+	return failure{
+		failed: make(chan struct{}),
+	}
+}
+
+func (f *failure) fail(err error) {
+	f.once.Do(func() {
+		f.err = err
+		close(f.failed)
+	})
+}
+
+// Failed is closed on the fatal error.
+func (f *failure) Failed() <-chan struct{} {
+	return f.failed
+}
+
+// Err returns the fatal error or nil.
+func (f *failure) Err() error {
+	select {
+	case <-f.failed:
+		return f.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+package reader
+
+import (
+	"sync"
+
+	"github.com/Fantom-foundation/go-opera/logger"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// Fanout writes the ordered events into the db and the additional sinks.
+// A slow sink holds up the others, so no events are lost.
+// Checkpoint is the block before the lowest one with the events which are not written by all the sinks
+// (or are held by the producer, see EventsBuffer), or the last sent block if all of them are written.
+type Fanout struct {
+	internal.Db
+	sinks []internal.Sink
+
+	checkpoint struct {
+		last idx.Block
+		sent idx.Block
+		// pending is a count of the not written (or held) events of the block
+		pending map[idx.Block]int
+		sync.Mutex
+	}
+
+	failure
+	logger.Instance
+}
+
+func NewFanout(db internal.Db, sinks ...internal.Sink) (*Fanout, error) {
+	last, err := db.GetLastBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fanout{
+		Db:       db,
+		sinks:    append([]internal.Sink{db}, sinks...),
+		failure:  newFailure(),
+		Instance: logger.New("fanout"),
+	}
+	f.checkpoint.last = last
+	f.checkpoint.pending = make(map[idx.Block]int)
+
+	return f, nil
+}
+
+// GetPruneCutoff implements internal.PruneState interface by the db one.
+func (f *Fanout) GetPruneCutoff() (internal.PruneCutoff, error) {
+	return pruneCutoff(f.Db)
+}
+
+// Load implements internal.Sink interface.
+// A failed sink gets no more events, so checkpoint stops and Load returns the sink error.
+func (f *Fanout) Load(events <-chan *internal.EventInfo) error {
+	var (
+		outputs = make([]chan *internal.EventInfo, len(f.sinks))
+		work    sync.WaitGroup
+	)
+	for i, sink := range f.sinks {
+		outputs[i] = make(chan *internal.EventInfo, 10)
+		work.Add(1)
+		go func(sink internal.Sink, output <-chan *internal.EventInfo) {
+			defer work.Done()
+			err := sink.Load(output)
+			if err == nil {
+				return
+			}
+			f.Log.Error("sink failure", "err", err)
+			f.fail(err)
+			for range output {
+				// not written
+			}
+		}(sink, outputs[i])
+	}
+
+	for info := range events {
+		f.sent(info)
+
+		var (
+			origin  = info
+			waiting = len(outputs)
+			mu      sync.Mutex
+		)
+		for _, output := range outputs {
+			copied := *origin
+			copied.Dispose = func() {
+				mu.Lock()
+				defer mu.Unlock()
+				waiting--
+				if waiting == 0 {
+					f.written(origin)
+				}
+			}
+			output <- &copied
+		}
+	}
+
+	for _, output := range outputs {
+		close(output)
+	}
+	work.Wait()
+
+	return f.Err()
+}
+
+func (f *Fanout) sent(info *internal.EventInfo) {
+	f.hold(info.Block)
+}
+
+func (f *Fanout) written(info *internal.EventInfo) {
+	defer info.Done()
+	f.release(info.Block)
+}
+
+// hold keeps the checkpoint before the block until release.
+func (f *Fanout) hold(block idx.Block) {
+	if block == internal.UnconfirmedBlock {
+		return
+	}
+
+	f.checkpoint.Lock()
+	defer f.checkpoint.Unlock()
+
+	f.checkpoint.pending[block]++
+	if f.checkpoint.sent < block {
+		f.checkpoint.sent = block
+	}
+}
+
+// release moves the checkpoint up to the block before the lowest pending one.
+func (f *Fanout) release(block idx.Block) {
+	if block == internal.UnconfirmedBlock {
+		return
+	}
+
+	f.checkpoint.Lock()
+	defer f.checkpoint.Unlock()
+
+	f.checkpoint.pending[block]--
+	if f.checkpoint.pending[block] == 0 {
+		delete(f.checkpoint.pending, block)
+	}
+
+	completed := f.checkpoint.sent
+	for n := range f.checkpoint.pending {
+		if n <= completed {
+			completed = n - 1
+		}
+	}
+	if completed > f.checkpoint.last && f.Err() == nil {
+		err := f.Db.SetLastBlock(completed)
+		if err != nil {
+			f.Log.Error("checkpoint failure", "err", err)
+			f.fail(err)
+			return
+		}
+		f.checkpoint.last = completed
+		f.Log.Debug("checkpoint", "block", completed)
+	}
+}
@@ -0,0 +1,59 @@
+package reader
+
+import (
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// The metrics are registered at the first use, so they are collected
+// if the application enables metrics before it runs the reader.
+
+func eventsFetchedCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/events/fetched", nil)
+}
+
+func placeholdersCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/events/placeholders", nil)
+}
+
+func placeholdersRecoveredCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/events/placeholders/recovered", nil)
+}
+
+func unverifiedCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/events/unverified", nil)
+}
+
+func reconnectsCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/rpc/reconnects", nil)
+}
+
+// rpcErrorsCounter returns RPC errors counter of the API method.
+func rpcErrorsCounter(method string) metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/rpc/errors/"+method, nil)
+}
+
+func bufferBacklogGauge() metrics.Gauge {
+	return metrics.GetOrRegisterGauge("dagreader/buffer/backlog", nil)
+}
+
+func bufferIncompleteGauge() metrics.Gauge {
+	return metrics.GetOrRegisterGauge("dagreader/buffer/incomplete", nil)
+}
+
+func bufferSpilledCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/buffer/spilled", nil)
+}
+
+func bufferGapsCounter() metrics.Counter {
+	return metrics.GetOrRegisterCounter("dagreader/buffer/gaps", nil)
+}
+
+func updateBlockGauges(cur, head int64) {
+	metrics.GetOrRegisterGauge("dagreader/block/current", nil).Update(cur)
+	metrics.GetOrRegisterGauge("dagreader/block/head", nil).Update(head)
+	lag := head - cur
+	if lag < 0 {
+		lag = 0
+	}
+	metrics.GetOrRegisterGauge("dagreader/block/lag", nil).Update(lag)
+}
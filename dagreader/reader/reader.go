@@ -0,0 +1,803 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/logger"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// finiteRetries is a number of failed attempts after which finite source is given up.
+const finiteRetries = 3
+
+type DagReader struct {
+	url     string
+	dial    Dialer
+	output  chan *internal.EventInfo
+	storage internal.Storage
+	done    chan struct{}
+	work    sync.WaitGroup
+	// requests of the missing events with their blocks
+	requests chan map[hash.Event]idx.Block
+
+	// retryInterval of the not found events recovery, 0 to disable
+	retryInterval time.Duration
+	// headsInterval of the DAG heads polling, 0 to disable
+	headsInterval time.Duration
+	// unconfirmed events, which are not confirmed by any block yet
+	unconfirmed map[hash.Event]*internal.EventInfo
+	// finite source has no new blocks, so reader stops after the last one
+	finite bool
+	// concurrency is a number of parallel event requests
+	concurrency int
+	// verifier of the fetched events, nil to trust them
+	verifier *verifier
+	// epochs to read, zero range reads all the blocks
+	epochs EpochRange
+	// epoch is the info of the epoch being read, nil if no block of the epochs range is read yet
+	epoch *internal.EpochInfo
+	// epochsRead is true when a block after the epochs range is reached
+	epochsRead bool
+	// cutoff of the pruned events, they are never read again
+	cutoff internal.PruneCutoff
+	// onValidators gets the validators of each read epoch, validatorsEpoch is the last of them
+	onValidators    func(idx.Epoch, []*internal.EpochValidator)
+	validatorsEpoch idx.Epoch
+
+	failure
+	logger.Instance
+}
+
+// storageError stops reading, as storage retries transient errors itself.
+type storageError struct {
+	error
+}
+
+func (e storageError) Unwrap() error {
+	return e.error
+}
+
+// NewReader reads DAG from the config source, config Db is used as storage only.
+func NewReader(cfg Config) *DagReader {
+	r := newReader(cfg.Source.URL, cfg.Source.Dial, cfg.Db)
+	r.finite = cfg.Source.Finite
+	r.retryInterval = cfg.RetryInterval
+	r.headsInterval = cfg.HeadsInterval
+	if cfg.Concurrency > 1 {
+		r.concurrency = cfg.Concurrency
+	}
+	if cfg.Verify {
+		r.verifier = newVerifier()
+	}
+	r.epochs = cfg.Epochs
+	r.onValidators = cfg.OnValidators
+	r.start(cfg.DagStart)
+	return r
+}
+
+func newReader(url string, dial Dialer, s internal.Storage) *DagReader {
+	return &DagReader{
+		url:         url,
+		dial:        dial,
+		output:      make(chan *internal.EventInfo, 10),
+		storage:     s,
+		concurrency: 1,
+		done:        make(chan struct{}),
+		requests:    make(chan map[hash.Event]idx.Block, 1),
+		unconfirmed: make(map[hash.Event]*internal.EventInfo),
+		failure:     newFailure(),
+		Instance:    logger.New("reader"),
+	}
+}
+
+func (r *DagReader) start(dagStart idx.Block) {
+	r.work.Add(1)
+	go r.background(dagStart)
+}
+
+func (r *DagReader) Close() {
+	if r.done == nil {
+		return
+	}
+	close(r.done)
+	r.work.Wait()
+	r.done = nil
+}
+
+// Events returns the read events, the channel is closed when reader stops (see Err).
+func (s *DagReader) Events() <-chan *internal.EventInfo {
+	return s.output
+}
+
+// stopOn fails reader if err is a storage one.
+func (s *DagReader) stopOn(err error) bool {
+	var serr storageError
+	if !errors.As(err, &serr) {
+		return false
+	}
+	s.Log.Error("storage failure, stop reading", "err", err)
+	s.fail(err)
+	return true
+}
+
+// Request the missing events again. It doesn't wait, so the request is skipped if reader is busy.
+func (s *DagReader) Request(missing map[hash.Event]idx.Block) {
+	select {
+	case s.requests <- missing:
+	default:
+		s.Log.Warn("skip request of missing events, reader is busy", "count", len(missing))
+	}
+}
+
+func (r *DagReader) background(dagStart idx.Block) {
+	defer r.work.Done()
+	defer close(r.output)
+	r.Log.Info("starting")
+	defer r.Log.Info("stopped")
+
+	var (
+		client   Client
+		err      error
+		maxBlock = big.NewInt(0)
+		sbscr    ethereum.Subscription
+		headers  = make(chan *types.Header, 1)
+		curBlock *big.Int
+
+		connected bool
+		failures  int
+	)
+
+	last, err := r.storage.GetLastBlock()
+	if err != nil {
+		r.stopOn(storageError{err})
+		return
+	}
+	// epochs range is located by the first block of its first epoch
+	located := r.epochs.IsZero()
+	if dagStart == AutoDagStart && located {
+		dagStart, err = r.dagStart()
+		if err != nil {
+			r.stopOn(err)
+			return
+		}
+		if dagStart != AutoDagStart {
+			r.Log.Info("cached first DAG block", "dagstart", dagStart)
+		}
+	}
+	if last > dagStart {
+		curBlock = big.NewInt(int64(last))
+	} else {
+		curBlock = big.NewInt(int64(dagStart))
+	}
+
+	r.Log.Info("start from", "block", curBlock)
+
+	disconnect := func() {
+		if sbscr != nil {
+			sbscr.Unsubscribe()
+			sbscr = nil
+		}
+		if client != nil {
+			client.Close()
+			client = nil
+			r.Log.Error("disonnect from", "url", r.url)
+		}
+	}
+	defer disconnect()
+
+	was := make(map[hash.Event]struct{})
+
+	var retry <-chan time.Time
+	if r.retryInterval > 0 {
+		ticker := time.NewTicker(r.retryInterval)
+		defer ticker.Stop()
+		retry = ticker.C
+	}
+	var heads <-chan time.Time
+	if r.headsInterval > 0 {
+		ticker := time.NewTicker(r.headsInterval)
+		defer ticker.Stop()
+		heads = ticker.C
+	}
+
+	unconfirmed, err := r.storage.GetBlockEvents(internal.UnconfirmedBlock)
+	if err != nil {
+		r.stopOn(storageError{err})
+		return
+	}
+	for _, info := range unconfirmed {
+		r.unconfirmed[info.Event.ID()] = info
+	}
+
+	for {
+		// client connect
+		for client == nil {
+			select {
+			case <-r.done:
+				return
+			default:
+			}
+			client, err = r.connect()
+			if err != nil && r.finite {
+				// datadir does not come up by itself
+				return
+			}
+			if err != nil {
+				disconnect()
+				delay()
+				continue
+			}
+			if _, ok := client.(PayloadClient); r.verifier != nil && !ok {
+				r.Log.Error("stop reading", "err", ErrNotVerifiable)
+				r.fail(ErrNotVerifiable)
+				return
+			}
+			if !located {
+				var first idx.Block
+				first, err = r.firstEpochBlock(client)
+				if r.stopOn(err) || err != nil && r.finite {
+					return
+				}
+				if err != nil {
+					disconnect()
+					delay()
+					continue
+				}
+				located = true
+				curBlock.SetUint64(uint64(first))
+				r.Log.Info("start from", "block", curBlock)
+			}
+			if dagStart == AutoDagStart && r.epochs.IsZero() {
+				dagStart, err = r.detectDagStart(client)
+				if r.stopOn(err) || err != nil && r.finite {
+					return
+				}
+				if err != nil {
+					disconnect()
+					delay()
+					continue
+				}
+				if curBlock.Cmp(big.NewInt(int64(dagStart))) < 0 {
+					curBlock.SetUint64(uint64(dagStart))
+					r.Log.Info("start from", "block", curBlock)
+				}
+			}
+			sbscr, err = r.subscribe(client, headers)
+			if err != nil {
+				disconnect()
+				delay()
+				continue
+			}
+			if connected {
+				reconnectsCounter().Inc(1)
+			}
+			connected = true
+		}
+
+		for curBlock.Cmp(maxBlock) <= 0 {
+			updateBlockGauges(curBlock.Int64(), maxBlock.Int64())
+			was, err = r.readEvents(curBlock, client, was)
+			if err != nil {
+				break
+			}
+			if r.epochsRead {
+				r.Log.Info("all epochs are read", "epochs", r.epochs)
+				return
+			}
+			curBlock.Add(curBlock, big.NewInt(1))
+			failures = 0
+
+			select {
+			case <-retry:
+				err = r.recoverPlaceholders(client)
+			case <-heads:
+				err = r.readHeads(client)
+			case missing := <-r.requests:
+				err = r.readMissing(client, missing)
+			default:
+			}
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			if r.stopOn(err) {
+				return
+			}
+			failures++
+			if r.finite && failures > finiteRetries {
+				r.Log.Error("stop reading finite source", "block", curBlock, "err", err)
+				return
+			}
+			disconnect()
+			delay()
+			continue
+		}
+
+		if r.epoch != nil {
+			// the epoch tail is served while the epoch is current only, before its sealing block
+			err = r.readHeads(client)
+			if r.stopOn(err) {
+				return
+			}
+		}
+
+		if r.finite && maxBlock.Sign() > 0 && curBlock.Cmp(maxBlock) > 0 {
+			r.Log.Info("all blocks are read", "last", maxBlock)
+			if r.epoch != nil {
+				// the last epoch is not sealed yet
+				if err := r.finishEpoch(client); err != nil && !r.stopOn(err) {
+					r.Log.Error("stop reading finite source", "epoch", r.epoch.Epoch, "err", err)
+				}
+			}
+			return
+		}
+
+		r.Log.Info("wait for next block")
+		select {
+		case b := <-headers:
+			if maxBlock.Cmp(b.Number) < 0 {
+				maxBlock.Set(b.Number)
+			}
+			updateBlockGauges(curBlock.Int64(), maxBlock.Int64())
+		case <-retry:
+			err = r.recoverPlaceholders(client)
+			if r.stopOn(err) {
+				return
+			}
+			if err != nil {
+				disconnect()
+				delay()
+			}
+		case <-heads:
+			err = r.readHeads(client)
+			if r.stopOn(err) {
+				return
+			}
+			if err != nil {
+				disconnect()
+				delay()
+			}
+		case missing := <-r.requests:
+			err = r.readMissing(client, missing)
+			if r.stopOn(err) {
+				return
+			}
+			if err != nil {
+				disconnect()
+				delay()
+			}
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (s *DagReader) readEvents(n *big.Int, client Client, was0 map[hash.Event]struct{}) (was1 map[hash.Event]struct{}, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	blk, err := client.BlockByNumber(ctx, n)
+	cancel()
+	if err != nil {
+		rpcErrorsCounter("BlockByNumber").Inc(1)
+		s.Log.Error("get block", "n", n, "err", err)
+		return
+	}
+	atropos := hash.Event(blk.Hash())
+	s.Log.Info("got block", "n", n, "atropos", atropos)
+
+	s.cutoff, err = pruneCutoff(s.storage)
+	if err != nil {
+		err = storageError{err}
+		return
+	}
+
+	if !s.epochs.IsZero() {
+		var in bool
+		in, err = s.epochBlock(client, idx.Block(n.Uint64()), atropos)
+		if err != nil {
+			return
+		}
+		if !in {
+			s.epochsRead = true
+			return was0, nil
+		}
+	}
+
+	err = s.passValidators(client, atropos.Epoch())
+	if err != nil {
+		return
+	}
+
+	// events of the sealed epochs will never be confirmed
+	for id := range s.unconfirmed {
+		if id.Epoch()+1 < atropos.Epoch() {
+			delete(s.unconfirmed, id)
+		}
+	}
+
+	was1 = make(map[hash.Event]struct{})
+	err = s.walk(client, atropos, internal.EventInfo{
+		Block: idx.Block(n.Uint64()),
+		Role:  "atropos",
+	}, was0, was1)
+
+	return
+}
+
+// recoverPlaceholders retries to get stored not found events and their missing ancestors.
+func (s *DagReader) recoverPlaceholders(client Client) error {
+	placeholders, err := s.storage.GetPlaceholders()
+	if err != nil {
+		return storageError{err}
+	}
+	if len(placeholders) < 1 {
+		return nil
+	}
+	s.Log.Info("retry not found events", "count", len(placeholders))
+
+	was := make(map[hash.Event]struct{})
+	for _, p := range placeholders {
+		err := s.walk(client, p.Event.ID(), internal.EventInfo{
+			Block:  p.Block,
+			Role:   internal.FoundRole(p.Role),
+			Update: true,
+		}, nil, was)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readMissing gets the requested events and their missing ancestors again,
+// not found ones become placeholders.
+func (s *DagReader) readMissing(client Client, missing map[hash.Event]idx.Block) error {
+	s.Log.Info("read missing events", "count", len(missing))
+
+	was := make(map[hash.Event]struct{})
+	for e, block := range missing {
+		if _, known := was[e]; known {
+			continue
+		}
+		stored, err := s.isStored(e)
+		if err != nil {
+			return err
+		}
+		if stored {
+			continue
+		}
+		err = s.walk(client, e, internal.EventInfo{
+			Block: block,
+		}, nil, was)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readHeads gets the DAG heads of the current epoch and their unknown ancestors,
+// which are not confirmed by any block yet.
+func (s *DagReader) readHeads(client Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	// -1 is for the current epoch, the node serves no heads of the others
+	heads, err := client.GetHeads(ctx, big.NewInt(-1))
+	cancel()
+	if headsNotServed(err) {
+		s.Log.Warn("heads are not served", "err", err)
+		return nil
+	}
+	if err != nil {
+		rpcErrorsCounter("GetHeads").Inc(1)
+		s.Log.Error("get heads", "err", err)
+		return err
+	}
+
+	if len(heads) > 0 {
+		err = s.passValidators(client, heads[0].Epoch())
+		if err != nil {
+			return err
+		}
+	}
+	err = s.walkHeads(client, heads, make(map[hash.Event]struct{}))
+	if err != nil {
+		return err
+	}
+	if s.epoch != nil && len(heads) > 0 && heads[0].Epoch() == s.epoch.Epoch {
+		s.epoch.Tail = true
+	}
+	return nil
+}
+
+// headsNotServed is true if the node has no heads API or no heads of the requested epoch,
+// it is not a connection failure.
+func headsNotServed(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "not available")
+}
+
+// walkHeads gets the unknown heads of the epochs range and their unknown ancestors,
+// which are not confirmed by any block yet.
+func (s *DagReader) walkHeads(client Client, heads hash.Events, was map[hash.Event]struct{}) error {
+	for _, h := range heads {
+		if !s.epochs.Contains(h.Epoch()) {
+			continue
+		}
+		if _, known := s.unconfirmed[h]; known {
+			continue
+		}
+		if _, known := was[h]; known {
+			continue
+		}
+		stored, err := s.isStored(h)
+		if err != nil {
+			return err
+		}
+		if stored {
+			continue
+		}
+
+		s.Log.Debug("detected head", "id", h)
+		err = s.walk(client, h, internal.EventInfo{
+			Block: internal.UnconfirmedBlock,
+		}, nil, was)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk gets the root event and its unknown ancestors and sends them to output.
+// The root event info is made from the template, the ancestors get the template block only.
+// Walk from confirmed block also confirms the known unconfirmed events.
+func (s *DagReader) walk(client Client, root hash.Event, template internal.EventInfo, was0, was1 map[hash.Event]struct{}) error {
+	queue := make(hash.Events, 0, 100)
+	queue = append(queue, root)
+
+	confirming := template.Block != internal.UnconfirmedBlock
+
+	for len(queue) > 0 {
+		// a batch of the queue tail is requested in parallel, then processed in the queue order
+		n := s.concurrency
+		if n > len(queue) {
+			n = len(queue)
+		}
+		batch := make(hash.Events, 0, n)
+		for _, e := range queue[len(queue)-n:] {
+			if _, was := was1[e]; was && e != root {
+				continue
+			}
+			batch = append(batch, e)
+		}
+		queue = queue[:len(queue)-n]
+		fetched := s.fetch(client, batch, confirming)
+
+		for i := len(batch) - 1; i >= 0; i-- {
+			e := batch[i]
+			if _, was := was1[e]; was && e != root {
+				continue
+			}
+
+			info := &internal.EventInfo{
+				Block: template.Block,
+			}
+			if e == root {
+				info.Role = template.Role
+				info.Update = template.Update
+			}
+
+			var event dag.Event
+			if prev, unconfirmed := s.unconfirmed[e]; unconfirmed && confirming {
+				delete(s.unconfirmed, e)
+				event = prev.Event
+				info.Integrity = prev.Integrity
+				info.Update = true
+				if internal.IsPlaceholder(prev.Role) {
+					info.Role = info.Role + internal.PlaceholderMark
+				}
+				s.Log.Debug("confirmed event", "block", info.Block, "id", e)
+			} else {
+				var err error
+				event, err = fetched[e].event, fetched[e].err
+				if err != nil {
+					if !strings.Contains(err.Error(), "not found") {
+						rpcErrorsCounter("GetEvent").Inc(1)
+						s.Log.Error("get event", "block", info.Block, "id", e, "err", err)
+						return err
+					}
+					if info.Update {
+						s.Log.Debug("still not found", "block", info.Block, "id", e)
+						continue
+					}
+					event = NotFoundEvent(e)
+					info.Role = info.Role + internal.PlaceholderMark
+					placeholdersCounter().Inc(1)
+				} else {
+					eventsFetchedCounter().Inc(1)
+					if info.Update {
+						placeholdersRecoveredCounter().Inc(1)
+						s.Log.Info("recovered event", "block", info.Block, "id", e)
+					}
+					info.Integrity = fetched[e].integrity
+					if IntegrityFailed(info.Integrity) {
+						s.Log.Warn("event fails verification", "block", info.Block, "id", e, "failed", info.Integrity)
+					}
+				}
+				if !confirming {
+					s.unconfirmed[e] = &internal.EventInfo{
+						Event:     event,
+						Role:      info.Role,
+						Integrity: info.Integrity,
+					}
+				}
+			}
+			info.Event = event
+
+			s.Log.Info("got event", "block", info.Block, "id", event.ID(), "role", info.Role)
+			select {
+			case s.output <- info:
+				was1[event.ID()] = struct{}{}
+			case <-s.done:
+				return fmt.Errorf("interrupted")
+			}
+
+			for _, p := range event.Parents() {
+				if _, was := was0[p]; was {
+					continue
+				}
+				if _, was := was1[p]; was {
+					continue
+				}
+				if _, unconfirmed := s.unconfirmed[p]; unconfirmed {
+					if confirming {
+						queue = append(queue, p)
+					}
+					continue
+				}
+				stored, err := s.isStored(p)
+				if err != nil {
+					return err
+				}
+				if stored {
+					was1[p] = struct{}{}
+					continue
+				}
+
+				s.Log.Debug("detected event", "id", event.ID(), "parent", p, "block", info.Block)
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isStored returns true if the event is stored or pruned, the pruned parents are boundary of the stored DAG.
+func (s *DagReader) isStored(e hash.Event) (bool, error) {
+	if s.cutoff.Pruned(e) {
+		return true, nil
+	}
+	stored, err := s.storage.HasEvent(e)
+	if err != nil {
+		return false, storageError{err}
+	}
+	return stored, nil
+}
+
+// pruneCutoff returns the cutoff of the db pruned events, zero if the db prunes nothing.
+func pruneCutoff(db internal.Storage) (internal.PruneCutoff, error) {
+	if state, ok := db.(internal.PruneState); ok {
+		return state.GetPruneCutoff()
+	}
+	return internal.PruneCutoff{}, nil
+}
+
+type fetchedEvent struct {
+	event dag.Event
+	// integrity is the failed checks of the verified event
+	integrity string
+	err       error
+}
+
+// fetch gets the events in parallel, except the known unconfirmed ones which are confirmed now.
+func (s *DagReader) fetch(client Client, ee hash.Events, confirming bool) map[hash.Event]fetchedEvent {
+	var (
+		res  = make(map[hash.Event]fetchedEvent, len(ee))
+		work sync.WaitGroup
+		mu   sync.Mutex
+	)
+	for e := range ee.Set() {
+		if _, unconfirmed := s.unconfirmed[e]; unconfirmed && confirming {
+			continue
+		}
+		work.Add(1)
+		go func(e hash.Event) {
+			defer work.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+			var fetched fetchedEvent
+			if s.verifier != nil {
+				fetched.event, fetched.integrity, fetched.err = s.verifier.GetEvent(ctx, client.(PayloadClient), e)
+			} else {
+				fetched.event, fetched.err = client.GetEvent(ctx, e)
+			}
+			cancel()
+
+			mu.Lock()
+			defer mu.Unlock()
+			res[e] = fetched
+		}(e)
+	}
+	work.Wait()
+
+	return res
+}
+
+// NotFoundEvent is a placeholder of the event which is detected but not found, it has ID only.
+func NotFoundEvent(id hash.Event) dag.Event {
+	e := dag.MutableBaseEvent{}
+
+	e.SetEpoch(id.Epoch())
+	e.SetLamport(id.Lamport())
+
+	var idTail [24]byte
+	copy(idTail[:], id[8:])
+
+	return e.Build(idTail)
+}
+
+func (s *DagReader) connect() (Client, error) {
+	client, err := s.dial(s.url)
+	if err != nil {
+		rpcErrorsCounter("Dial").Inc(1)
+		s.Log.Error("connect to", "url", s.url, "err", err)
+		return nil, err
+	}
+	s.Log.Info("connect to", "url", s.url)
+	return client, nil
+}
+
+func (s *DagReader) subscribe(client Client, headers chan *types.Header) (sbscr ethereum.Subscription, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	try(func() error {
+		sbscr, err = client.SubscribeNewHead(ctx, headers)
+		return err
+	})
+	if err != nil {
+		rpcErrorsCounter("SubscribeNewHead").Inc(1)
+		s.Log.Error("subscribe to", "url", s.url, "err", err)
+		return
+	}
+	s.Log.Info("subscribe to", "url", s.url)
+	return
+}
+
+func delay() {
+	<-time.After(2 * time.Second)
+}
+
+func try(f func() error) (err error) {
+	defer func() {
+		if catch := recover(); catch != nil {
+			err = fmt.Errorf("client panic: %v", catch)
+		}
+	}()
+
+	err = f()
+	return
+}
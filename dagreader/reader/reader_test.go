@@ -0,0 +1,751 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag/tdag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// fakeNode is a scripted opera node which serves the synthetic DAG.
+type fakeNode struct {
+	events  map[hash.Event]dag.Event
+	atropoi hash.Events
+	// blocks is a count of the served blocks
+	blocks int
+	// hidden events are not found
+	hidden hash.EventsSet
+	// failures is a count of the GetEvent calls to fail
+	failures int
+	dials    int
+	// heads of the epochs, nil if heads are not supported, only the current epoch heads are served
+	heads map[idx.Epoch]hash.Events
+
+	sync.Mutex
+}
+
+func newFakeNode(epoch idx.Epoch, seed int64) *fakeNode {
+	n := &fakeNode{
+		events: make(map[hash.Event]dag.Event),
+		hidden: hash.EventsSet{},
+	}
+
+	nodes := tdag.GenNodes(5)
+	var ordered dag.Events
+	tdag.ForEachRandEvent(nodes, 20, 3, rand.New(rand.NewSource(seed)), tdag.ForEachEvent{
+		Build: func(e dag.MutableEvent, name string) error {
+			e.SetEpoch(epoch)
+			return nil
+		},
+		Process: func(e dag.Event, name string) {
+			n.events[e.ID()] = e
+			ordered = append(ordered, e)
+		},
+	})
+	// each 10th event is an atropos of the next block
+	for i := 9; i < len(ordered); i += 10 {
+		n.atropoi = append(n.atropoi, ordered[i].ID())
+	}
+	n.blocks = len(n.atropoi)
+
+	return n
+}
+
+func (n *fakeNode) dial(url string) (Client, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	n.dials++
+	return n, nil
+}
+
+func (n *fakeNode) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	i := int(number.Int64())
+	if i < 1 || i > n.blocks {
+		return nil, ethereum.NotFound
+	}
+	header := &types.Header{
+		Number: new(big.Int).Set(number),
+	}
+	header.SetExternalHash(common.Hash(n.atropoi[i-1]))
+	return types.NewBlockWithHeader(header), nil
+}
+
+func (n *fakeNode) GetEvent(ctx context.Context, h hash.Event) (dag.Event, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.failures > 0 {
+		n.failures--
+		return nil, errors.New("connection lost")
+	}
+	e, ok := n.events[h]
+	if !ok || n.hidden.Contains(h) {
+		return nil, ethereum.NotFound
+	}
+	return e, nil
+}
+
+func (n *fakeNode) GetHeads(ctx context.Context, epoch *big.Int) (hash.Events, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.heads == nil {
+		return nil, errors.New("the method dag_getHeads does not exist/is not available")
+	}
+	// like go-opera, the current epoch is of the last block and only its heads are served,
+	// nil is for the latest sealed epoch and -1 is for the current one
+	current := n.atropoi[n.blocks-1].Epoch()
+	requested := current
+	switch {
+	case epoch == nil:
+		requested = current - 1
+	case epoch.Sign() >= 0:
+		requested = idx.Epoch(epoch.Uint64())
+	}
+	if requested != current {
+		return nil, errors.New("heads for previous epochs are not available")
+	}
+	return n.heads[current], nil
+}
+
+// newEpochsNode serves the synthetic DAGs of the epochs one after another,
+// the events after the last atropos of the epoch are never confirmed.
+func newEpochsNode(epochs idx.Epoch, seed int64) *fakeNode {
+	n := &fakeNode{
+		events: make(map[hash.Event]dag.Event),
+		hidden: hash.EventsSet{},
+		heads:  make(map[idx.Epoch]hash.Events),
+	}
+	for epoch := idx.Epoch(1); epoch <= epochs; epoch++ {
+		sub := newFakeNode(epoch, seed+int64(epoch))
+		parents := hash.EventsSet{}
+		for id, e := range sub.events {
+			n.events[id] = e
+			parents.Add(e.Parents()...)
+		}
+		for id := range sub.events {
+			if !parents.Contains(id) {
+				n.heads[epoch] = append(n.heads[epoch], id)
+			}
+		}
+		n.atropoi = append(n.atropoi, sub.atropoi...)
+	}
+	n.blocks = len(n.atropoi)
+	return n
+}
+
+// SubscribeNewHead notifies of the last block, also when the node serves more blocks later.
+func (n *fakeNode) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		notified := 0
+		for {
+			n.Lock()
+			blocks := n.blocks
+			n.Unlock()
+
+			if blocks > notified {
+				header := &types.Header{
+					Number: big.NewInt(int64(blocks)),
+				}
+				select {
+				case ch <- header:
+					notified = blocks
+				case <-quit:
+					return nil
+				}
+			}
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// serve makes the node serve the first blocks.
+func (n *fakeNode) serve(blocks int) {
+	n.Lock()
+	defer n.Unlock()
+	n.blocks = blocks
+}
+
+func (n *fakeNode) Close() {}
+
+// confirmed returns the events confirmed by the served blocks
+// and reachable by the reader (parents of hidden events are unknown).
+func (n *fakeNode) confirmed() hash.EventsSet {
+	n.Lock()
+	defer n.Unlock()
+
+	set := hash.EventsSet{}
+	queue := n.atropoi[:n.blocks].Copy()
+	for len(queue) > 0 {
+		e := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if set.Contains(e) {
+			continue
+		}
+		set.Add(e)
+		if !n.hidden.Contains(e) {
+			queue = append(queue, n.events[e].Parents()...)
+		}
+	}
+	return set
+}
+
+// recorder is a sink which checks the events order and counts duplicates.
+type recorder struct {
+	t      *testing.T
+	loaded map[hash.Event]int
+	sync.Mutex
+}
+
+func newRecorder(t *testing.T) *recorder {
+	return &recorder{
+		t:      t,
+		loaded: make(map[hash.Event]int),
+	}
+}
+
+func (r *recorder) Load(events <-chan *internal.EventInfo) error {
+	for info := range events {
+		r.Lock()
+		for _, p := range info.Event.Parents() {
+			if r.loaded[p] == 0 && !r.t.Failed() {
+				r.t.Errorf("event %s is loaded before its parent %s", info.Event.ID(), p)
+			}
+		}
+		r.loaded[info.Event.ID()]++
+		r.Unlock()
+		info.Done()
+	}
+	return nil
+}
+
+func (r *recorder) count(e hash.Event) int {
+	r.Lock()
+	defer r.Unlock()
+	return r.loaded[e]
+}
+
+// readAll reads all the node blocks into db.
+func readAll(t *testing.T, node *fakeNode, db internal.Db, sinks ...internal.Sink) {
+	readSource(t, Source{URL: "fake", Dial: node.dial, Finite: true}, db, sinks...)
+	waitStored(t, node, db)
+}
+
+// readSource reads the finite source into db.
+func readSource(t *testing.T, src Source, db internal.Db, sinks ...internal.Sink) {
+	cfg := DefaultConfig()
+	cfg.Source = src
+	cfg.Db = db
+	cfg.RetryInterval = 0
+
+	res, err := Run(context.Background(), cfg, sinks...)
+	require.NoError(t, err)
+	require.Zero(t, res.Dropped)
+}
+
+func hasEvent(t *testing.T, db internal.Storage, e hash.Event) bool {
+	has, err := db.HasEvent(e)
+	require.NoError(t, err)
+	return has
+}
+
+func getEvent(t *testing.T, db internal.Storage, e hash.Event) *internal.EventInfo {
+	info, err := db.GetEvent(e)
+	require.NoError(t, err)
+	return info
+}
+
+func lastBlock(t *testing.T, db internal.Storage) idx.Block {
+	last, err := db.GetLastBlock()
+	require.NoError(t, err)
+	return last
+}
+
+func getPlaceholders(t *testing.T, db internal.Storage) []*internal.EventInfo {
+	placeholders, err := db.GetPlaceholders()
+	require.NoError(t, err)
+	return placeholders
+}
+
+// waitStored waits for the node events are stored into db.
+func waitStored(t *testing.T, node *fakeNode, db internal.Db) {
+	expected := node.confirmed()
+	require.Eventually(t, func() bool {
+		for e := range expected {
+			if !hasEvent(t, db, e) {
+				return false
+			}
+		}
+		return lastBlock(t, db) == idx.Block(node.blocks)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestReaderEndToEnd(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 0)
+	db := internal.NewMemDb()
+	rec := newRecorder(t)
+
+	readAll(t, node, db, rec)
+
+	for e := range node.confirmed() {
+		require.Equal(1, rec.count(e), e.String())
+		require.Equal(node.events[e].Parents(), getEvent(t, db, e).Event.Parents())
+	}
+	for i, atropos := range node.atropoi {
+		info := getEvent(t, db, atropos)
+		require.Equal("atropos", info.Role)
+		require.Equal(idx.Block(i+1), info.Block)
+	}
+	require.Empty(getPlaceholders(t, db))
+
+	last := idx.Block(len(node.atropoi))
+	snapshot, err := db.GetSnapshot(last)
+	require.NoError(err)
+	require.Equal(len(node.confirmed()), snapshot.Events)
+	require.Equal([]*internal.EventInfo{snapshot.Atropos}, snapshot.Frontier)
+	require.Equal(node.atropoi[last-1], snapshot.Atropos.Event.ID())
+
+	for _, head := range snapshot.Heads {
+		for e := range node.confirmed() {
+			if e.Lamport() > head.Event.Lamport() {
+				require.NotEqual(head.Event.Creator(), node.events[e].Creator(), "later event of the head creator")
+			}
+		}
+	}
+
+	// the middle block knows the events which are confirmed later but are not after its atropos
+	mid := last / 2
+	midAtropos := node.atropoi[mid-1]
+	known := hash.EventsSet{}
+	for e := range node.confirmed() {
+		if getEvent(t, db, e).Block <= mid || e.Lamport() <= midAtropos.Lamport() {
+			known.Add(e)
+		}
+	}
+	frontier := known.Copy()
+	for e := range known {
+		frontier.Erase(node.events[e].Parents()...)
+	}
+	snapshot, err = db.GetSnapshot(mid)
+	require.NoError(err)
+	require.Equal(len(known), snapshot.Events)
+	require.Len(snapshot.Frontier, len(frontier))
+	for _, info := range snapshot.Frontier {
+		require.True(frontier.Contains(info.Event.ID()), info.Event.ID().String())
+	}
+	require.Greater(len(frontier), 1, "frontier is not the atropos only")
+}
+
+func TestReaderConcurrency(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 7)
+	cfg := DefaultConfig()
+	cfg.Source = Source{URL: "fake", Dial: node.dial, Finite: true}
+	cfg.Db = NewMemDb()
+	cfg.Concurrency = 4
+
+	var (
+		loaded = make(map[hash.Event]int)
+		mu     sync.Mutex
+	)
+	res, err := Run(context.Background(), cfg, SinkFunc(func(info *EventInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range info.Event.Parents() {
+			if loaded[p] == 0 && !t.Failed() {
+				t.Errorf("event %s is loaded before its parent %s", info.Event.ID(), p)
+			}
+		}
+		loaded[info.Event.ID()]++
+	}))
+	require.NoError(err)
+	require.Zero(res.Dropped)
+	require.Equal(idx.Block(node.blocks), res.Resume)
+
+	for e := range node.confirmed() {
+		require.Equal(1, loaded[e], e.String())
+	}
+}
+
+func TestReaderResume(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 1)
+	db := internal.NewMemDb()
+	rec := newRecorder(t)
+
+	all := node.blocks
+	node.blocks = all / 2
+	readAll(t, node, db, rec)
+	firstRun := node.confirmed()
+
+	node.blocks = all
+	readAll(t, node, db, rec)
+
+	for e := range node.confirmed() {
+		require.Equal(1, rec.count(e), "duplicate %s, first run %v", e.String(), firstRun.Contains(e))
+	}
+}
+
+func TestReaderPlaceholders(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 2)
+	db := internal.NewMemDb()
+
+	// a parent of the last atropos, which is not atropos itself
+	var missing hash.Event
+	atropoi := node.atropoi.Set()
+	for _, p := range node.events[node.atropoi[len(node.atropoi)-1]].Parents() {
+		if !atropoi.Contains(p) {
+			missing = p
+			break
+		}
+	}
+	require.NotEqual(hash.ZeroEvent, missing)
+	node.hidden.Add(missing)
+
+	readAll(t, node, db)
+
+	placeholders := getPlaceholders(t, db)
+	require.Len(placeholders, 1)
+	require.Equal(missing, placeholders[0].Event.ID())
+	require.True(internal.IsPlaceholder(placeholders[0].Role))
+}
+
+// prunedDb is pruned up to the cutoff.
+type prunedDb struct {
+	*internal.MemDb
+	cutoff internal.PruneCutoff
+}
+
+func (db *prunedDb) GetPruneCutoff() (internal.PruneCutoff, error) {
+	return db.cutoff, nil
+}
+
+func TestReaderPruned(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 6)
+	all := node.blocks
+	pruned := all / 2
+	node.blocks = pruned
+	ancestors := node.confirmed()
+	node.blocks = all
+
+	// db is pruned up to the block, the events which are confirmed later reference the pruned ones
+	atropos := node.atropoi[pruned-1]
+	db := &prunedDb{
+		MemDb: internal.NewMemDb(),
+		cutoff: internal.PruneCutoff{
+			BlockEpoch: atropos.Epoch(),
+			Lamport:    atropos.Lamport(),
+		},
+	}
+	require.NoError(db.SetLastBlock(idx.Block(pruned)))
+
+	var (
+		loaded = make(map[hash.Event]int)
+		mu     sync.Mutex
+	)
+	readSource(t, Source{URL: "fake", Dial: node.dial, Finite: true}, db, SinkFunc(func(info *EventInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		loaded[info.Event.ID()]++
+	}))
+	require.Equal(idx.Block(all), lastBlock(t, db))
+	require.NotEmpty(loaded)
+
+	for e := range node.confirmed() {
+		switch {
+		case ancestors.Contains(e):
+			require.Zero(loaded[e], "pruned %s is read again", e.String())
+		case !db.cutoff.Pruned(e):
+			require.Equal(1, loaded[e], e.String())
+			require.True(hasEvent(t, db, e), e.String())
+		}
+	}
+	require.Empty(getPlaceholders(t, db))
+}
+
+func TestReaderDagStart(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 4)
+	start, err := DetectDagStart(node)
+	require.NoError(err)
+	require.Equal(idx.Block(1), start)
+
+	// genesis blocks have no DAG, their hashes are not events
+	for i := 0; i < 5; i++ {
+		node.atropoi[i] = hash.Event{byte(i + 1)}
+	}
+	start, err = DetectDagStart(node)
+	require.NoError(err)
+	require.Equal(idx.Block(6), start)
+
+	db := internal.NewMemDb()
+	cfg := DefaultConfig()
+	cfg.Source = Source{URL: "fake", Dial: node.dial, Finite: true}
+	cfg.Db = db
+	cfg.DagStart = AutoDagStart
+	cfg.RetryInterval = 0
+	res, err := Run(context.Background(), cfg)
+	require.NoError(err)
+	require.Equal(idx.Block(node.blocks), res.Resume)
+	require.Empty(getPlaceholders(t, db))
+	cached, err := db.GetDagStart()
+	require.NoError(err)
+	require.Equal(idx.Block(6), cached)
+
+	node.blocks = 5
+	_, err = DetectDagStart(node)
+	require.Equal(errNoDag, err)
+}
+
+func TestReaderEpochs(t *testing.T) {
+	require := require.New(t)
+
+	node := newEpochsNode(3, 5)
+	db := internal.NewMemDb()
+	rec := newRecorder(t)
+
+	var first, sealing idx.Block
+	for i, atropos := range node.atropoi {
+		if atropos.Epoch() == 2 && first == 0 {
+			first = idx.Block(i + 1)
+		}
+		if atropos.Epoch() == 2 {
+			sealing = idx.Block(i + 1)
+		}
+	}
+	// epoch 2 is current until the node serves the blocks of epoch 3
+	node.serve(int(sealing))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go func() {
+		for _, h := range node.heads[2] {
+			for has, _ := db.HasEvent(h); !has && ctx.Err() == nil; has, _ = db.HasEvent(h) {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+		node.serve(len(node.atropoi))
+	}()
+
+	cfg := DefaultConfig()
+	cfg.Source = Source{URL: "fake", Dial: node.dial}
+	cfg.Db = db
+	cfg.RetryInterval = 0
+	cfg.Epochs = EpochRange{From: 2, To: 2}
+	res, err := Run(ctx, cfg, rec)
+	require.NoError(err)
+	require.NoError(ctx.Err(), "stopped after the range, not on timeout")
+	require.Zero(res.Dropped)
+	require.Zero(res.Resume, "checkpoint is moved")
+
+	tail := 0
+	for id := range node.events {
+		require.Equal(id.Epoch() == 2, hasEvent(t, db, id), id.String())
+		if id.Epoch() == 2 {
+			require.Equal(1, rec.count(id), id.String())
+			if getEvent(t, db, id).Block == internal.UnconfirmedBlock {
+				tail++
+			}
+		}
+	}
+	require.NotZero(tail)
+
+	info, err := db.GetEpochInfo(2)
+	require.NoError(err)
+	require.Equal(&internal.EpochInfo{
+		Epoch:        2,
+		FirstBlock:   first,
+		SealingBlock: sealing,
+		Tail:         true,
+	}, info)
+	for _, epoch := range []idx.Epoch{1, 3} {
+		info, err = db.GetEpochInfo(epoch)
+		require.NoError(err)
+		require.Nil(info)
+	}
+
+	// the tail of the sealed epoch is not served anymore
+	db = internal.NewMemDb()
+	cfg.Source.Finite = true
+	cfg.Db = db
+	_, err = Run(context.Background(), cfg)
+	require.NoError(err)
+	info, err = db.GetEpochInfo(2)
+	require.NoError(err)
+	require.False(info.Tail)
+	confirmed := node.confirmed()
+	for _, h := range node.heads[2] {
+		require.Equal(confirmed.Contains(h), hasEvent(t, db, h), h.String())
+	}
+
+	// the last epoch is not sealed
+	db = internal.NewMemDb()
+	cfg.Db = db
+	cfg.Epochs = EpochRange{From: 3, To: 5}
+	var validators []idx.Epoch
+	cfg.OnValidators = func(epoch idx.Epoch, vv []*EpochValidator) {
+		require.Nil(vv, "node serves no validators")
+		validators = append(validators, epoch)
+	}
+	_, err = Run(context.Background(), cfg)
+	require.NoError(err)
+	require.Equal([]idx.Epoch{3}, validators)
+	info, err = db.GetEpochInfo(3)
+	require.NoError(err)
+	require.Equal(sealing+1, info.FirstBlock)
+	require.Zero(info.SealingBlock)
+	require.True(info.Tail)
+	for _, h := range node.heads[3] {
+		require.True(hasEvent(t, db, h), h.String())
+	}
+}
+
+func TestParseEpochRange(t *testing.T) {
+	require := require.New(t)
+
+	r, err := ParseEpochRange("3..5")
+	require.NoError(err)
+	require.Equal(EpochRange{From: 3, To: 5}, r)
+	require.True(r.Contains(4))
+	require.False(r.Contains(6))
+
+	r, err = ParseEpochRange("7")
+	require.NoError(err)
+	require.Equal(EpochRange{From: 7, To: 7}, r)
+
+	for _, s := range []string{"", "0", "a..5", "5..", "5..3"} {
+		_, err = ParseEpochRange(s)
+		require.Error(err, s)
+	}
+}
+
+func TestReaderReconnect(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 3)
+	node.failures = 1
+	db := internal.NewMemDb()
+	rec := newRecorder(t)
+
+	readAll(t, node, db, rec)
+
+	require.Equal(2, node.dials)
+	for e := range node.confirmed() {
+		require.Equal(1, rec.count(e), e.String())
+	}
+}
+
+// failingDb fails to write the checkpoint after the block.
+type failingDb struct {
+	*internal.MemDb
+	after idx.Block
+}
+
+var errDbDown = errors.New("db is down")
+
+func (db *failingDb) SetLastBlock(num idx.Block) error {
+	if num > db.after {
+		return errDbDown
+	}
+	return db.MemDb.SetLastBlock(num)
+}
+
+func TestReaderStorageFailure(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 5)
+	db := &failingDb{MemDb: internal.NewMemDb(), after: 2}
+
+	cfg := DefaultConfig()
+	cfg.Source = Source{URL: "fake", Dial: node.dial}
+	cfg.Db = db
+	cfg.RetryInterval = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := Run(ctx, cfg)
+	require.ErrorIs(err, errDbDown)
+	require.NoError(ctx.Err(), "stopped on failure, not on timeout")
+	require.Equal(idx.Block(2), res.Resume)
+}
+
+func TestRecordReplay(t *testing.T) {
+	require := require.New(t)
+
+	node := newFakeNode(1, 4)
+	path := filepath.Join(t.TempDir(), "session.ndjson")
+
+	recorder, err := NewRecorder(path)
+	require.NoError(err)
+	recorded := internal.NewMemDb()
+	readSource(t, recorder.Wrap(Source{URL: "fake", Dial: node.dial, Finite: true}), recorded)
+	waitStored(t, node, recorded)
+	require.NoError(recorder.Close())
+
+	replayed := internal.NewMemDb()
+	readSource(t, ReplaySource(path, 0), replayed)
+	waitStored(t, node, replayed)
+
+	for e := range node.confirmed() {
+		a, b := getEvent(t, recorded, e), getEvent(t, replayed, e)
+		require.Equal(a.Block, b.Block)
+		require.Equal(a.Role, b.Role)
+		require.Equal(a.Event.Parents(), b.Event.Parents())
+		require.Equal(a.Event.Creator(), b.Event.Creator())
+	}
+}
+
+func TestReplayTiming(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "session.ndjson")
+	session := `{"method":"BlockByNumber","at":0,"took":10000000,"number":1,"hash":"0x01"}
+{"method":"BlockByNumber","at":400000000,"took":10000000,"number":2,"hash":"0x02"}
+`
+	require.NoError(os.WriteFile(path, []byte(session), 0600))
+
+	src := ReplaySource(path, 4)
+	client, err := src.Dial(src.URL)
+	require.NoError(err)
+	start := time.Now()
+
+	_, err = client.BlockByNumber(context.Background(), big.NewInt(1))
+	require.NoError(err)
+	_, err = client.BlockByNumber(context.Background(), big.NewInt(2))
+	require.NoError(err)
+	// the gap between the requests is replayed, not the request durations only
+	require.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+}
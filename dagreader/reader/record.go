@@ -0,0 +1,258 @@
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/logger"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// rpcRecord is a request with response of the recorded session, one per line of the file.
+type rpcRecord struct {
+	Method string `json:"method"`
+	// At is a request time since the session start
+	At time.Duration `json:"at"`
+	// Took is a request duration
+	Took time.Duration `json:"took,omitempty"`
+
+	// Number is a block number of BlockByNumber and NewHead, or epoch of GetHeads (nil for the latest)
+	Number *big.Int `json:"number,omitempty"`
+	// ID of GetEvent request
+	ID string `json:"id,omitempty"`
+
+	// Hash is a block hash (atropos) of BlockByNumber response
+	Hash  string         `json:"hash,omitempty"`
+	Event *recordedEvent `json:"event,omitempty"`
+	Heads []string       `json:"heads,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
+
+// recordedEvent is the event fields which DagReader uses.
+type recordedEvent struct {
+	ID      string          `json:"id"`
+	Seq     idx.Event       `json:"seq"`
+	Frame   idx.Frame       `json:"frame"`
+	Creator idx.ValidatorID `json:"creator"`
+	Parents []string        `json:"parents"`
+}
+
+func newRecordedEvent(e dag.Event) *recordedEvent {
+	return &recordedEvent{
+		ID:      eventHex(e.ID()),
+		Seq:     e.Seq(),
+		Frame:   e.Frame(),
+		Creator: e.Creator(),
+		Parents: eventsHex(e.Parents()),
+	}
+}
+
+func (r *recordedEvent) Event() (dag.Event, error) {
+	id, err := internal.ParseEventID(r.ID)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := parseEvents(r.Parents)
+	if err != nil {
+		return nil, err
+	}
+
+	e := dag.MutableBaseEvent{}
+	e.SetEpoch(id.Epoch())
+	e.SetLamport(id.Lamport())
+	e.SetSeq(r.Seq)
+	e.SetFrame(r.Frame)
+	e.SetCreator(r.Creator)
+	e.SetParents(parents)
+
+	var idTail [24]byte
+	copy(idTail[:], id[8:])
+	return e.Build(idTail), nil
+}
+
+// Recorder logs every request and response of the reader to file.
+type Recorder struct {
+	file  *os.File
+	enc   *json.Encoder
+	start time.Time
+	// err is the first write error, the session is incomplete after it
+	err error
+	sync.Mutex
+
+	logger.Instance
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		file:     file,
+		enc:      json.NewEncoder(file),
+		start:    time.Now(),
+		Instance: logger.New("recorder"),
+	}, nil
+}
+
+// Close returns the first write error if any.
+func (r *Recorder) Close() error {
+	r.Lock()
+	defer r.Unlock()
+
+	err := r.file.Close()
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+// Wrap source to record its session.
+func (r *Recorder) Wrap(src Source) Source {
+	dial := src.Dial
+	src.Dial = func(url string) (Client, error) {
+		client, err := dial(url)
+		if err != nil {
+			return nil, err
+		}
+		return &recordingClient{
+			Client: client,
+			rec:    r,
+		}, nil
+	}
+	return src
+}
+
+func (r *Recorder) write(rec *rpcRecord, started time.Time, err error) {
+	rec.At = started.Sub(r.start)
+	rec.Took = time.Since(started)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.enc.Encode(rec); err != nil && r.err == nil {
+		r.err = err
+		r.Log.Error("record request", "method", rec.Method, "err", err)
+	}
+}
+
+type recordingClient struct {
+	Client
+	rec *Recorder
+}
+
+func (c *recordingClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	started := time.Now()
+	blk, err := c.Client.BlockByNumber(ctx, number)
+
+	rec := &rpcRecord{
+		Method: "BlockByNumber",
+		Number: number,
+	}
+	if err == nil {
+		rec.Hash = blk.Hash().Hex()
+	}
+	c.rec.write(rec, started, err)
+
+	return blk, err
+}
+
+func (c *recordingClient) GetEvent(ctx context.Context, h hash.Event) (dag.Event, error) {
+	started := time.Now()
+	e, err := c.Client.GetEvent(ctx, h)
+
+	rec := &rpcRecord{
+		Method: "GetEvent",
+		ID:     eventHex(h),
+	}
+	if err == nil {
+		rec.Event = newRecordedEvent(e)
+	}
+	c.rec.write(rec, started, err)
+
+	return e, err
+}
+
+func (c *recordingClient) GetHeads(ctx context.Context, epoch *big.Int) (hash.Events, error) {
+	started := time.Now()
+	heads, err := c.Client.GetHeads(ctx, epoch)
+
+	rec := &rpcRecord{
+		Method: "GetHeads",
+		Number: epoch,
+		Heads:  eventsHex(heads),
+	}
+	c.rec.write(rec, started, err)
+
+	return heads, err
+}
+
+func (c *recordingClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	headers := make(chan *types.Header, 1)
+	sub, err := c.Client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case h := <-headers:
+				c.rec.write(&rpcRecord{
+					Method: "NewHead",
+					Number: h.Number,
+				}, time.Now(), nil)
+				select {
+				case ch <- h:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+func eventHex(e hash.Event) string {
+	return hexutil.Encode(e.Bytes())
+}
+
+func eventsHex(ee hash.Events) []string {
+	res := make([]string, len(ee))
+	for i, e := range ee {
+		res[i] = eventHex(e)
+	}
+	return res
+}
+
+func parseEvents(ss []string) (hash.Events, error) {
+	res := make(hash.Events, len(ss))
+	for i, s := range ss {
+		e, err := internal.ParseEventID(s)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = e
+	}
+	return res, nil
+}
@@ -0,0 +1,192 @@
+package reader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// ReplaySource serves the recorded session back instead of a live node.
+// Responses come at the recorded time since the session start divided by speed, 0 speed means no delays.
+func ReplaySource(path string, speed float64) Source {
+	var (
+		session *replaySession
+		err     error
+		once    sync.Once
+	)
+	return Source{
+		URL: path,
+		Dial: func(path string) (Client, error) {
+			once.Do(func() {
+				session, err = loadSession(path, speed)
+			})
+			if err != nil {
+				return nil, err
+			}
+			return session, nil
+		},
+		Finite: true,
+	}
+}
+
+// replaySession is a Client of the recorded responses.
+// The same request gets the recorded responses in turn, the last one is repeated.
+type replaySession struct {
+	speed float64
+	// start of the replay, the recorded times are relative to it
+	start     time.Time
+	responses map[string][]*rpcRecord
+	lastHead  *big.Int
+
+	sync.Mutex
+}
+
+func loadSession(path string, speed float64) (*replaySession, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	s := &replaySession{
+		speed:     speed,
+		start:     time.Now(),
+		responses: make(map[string][]*rpcRecord),
+		lastHead:  big.NewInt(0),
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		rec := new(rpcRecord)
+		err = json.Unmarshal(scanner.Bytes(), rec)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+
+		switch rec.Method {
+		case "NewHead":
+			if s.lastHead.Cmp(rec.Number) < 0 {
+				s.lastHead.Set(rec.Number)
+			}
+			continue
+		case "BlockByNumber":
+			if rec.Error == "" && s.lastHead.Cmp(rec.Number) < 0 {
+				s.lastHead.Set(rec.Number)
+			}
+		}
+		key := requestKey(rec.Method, rec.Number, rec.ID)
+		s.responses[key] = append(s.responses[key], rec)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func requestKey(method string, number *big.Int, id string) string {
+	if number == nil {
+		return fmt.Sprintf("%s:nil:%s", method, id)
+	}
+	return fmt.Sprintf("%s:%s:%s", method, number, id)
+}
+
+// response returns the recorded response at the recorded time, the gaps between the requests are kept.
+// A late request is responded at once.
+func (s *replaySession) response(ctx context.Context, method string, number *big.Int, id string) (*rpcRecord, error) {
+	s.Lock()
+	key := requestKey(method, number, id)
+	queue := s.responses[key]
+	if len(queue) < 1 {
+		s.Unlock()
+		return nil, fmt.Errorf("request %s is not recorded", key)
+	}
+	rec := queue[0]
+	if len(queue) > 1 {
+		s.responses[key] = queue[1:]
+	}
+	s.Unlock()
+
+	if s.speed > 0 {
+		due := s.start.Add(time.Duration(float64(rec.At+rec.Took) / s.speed))
+		select {
+		case <-time.After(time.Until(due)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	switch rec.Error {
+	case "":
+		return rec, nil
+	case ethereum.NotFound.Error():
+		return nil, ethereum.NotFound
+	default:
+		return nil, errors.New(rec.Error)
+	}
+}
+
+func (s *replaySession) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	rec, err := s.response(ctx, "BlockByNumber", number, "")
+	if err != nil {
+		return nil, err
+	}
+
+	header := &types.Header{
+		Number: new(big.Int).Set(number),
+	}
+	header.SetExternalHash(common.HexToHash(rec.Hash))
+	return types.NewBlockWithHeader(header), nil
+}
+
+func (s *replaySession) GetEvent(ctx context.Context, h hash.Event) (dag.Event, error) {
+	rec, err := s.response(ctx, "GetEvent", nil, eventHex(h))
+	if err != nil {
+		return nil, err
+	}
+	if rec.Event == nil {
+		return nil, ethereum.NotFound
+	}
+	return rec.Event.Event()
+}
+
+func (s *replaySession) GetHeads(ctx context.Context, epoch *big.Int) (hash.Events, error) {
+	rec, err := s.response(ctx, "GetHeads", epoch, "")
+	if err != nil {
+		return nil, err
+	}
+	return parseEvents(rec.Heads)
+}
+
+// SubscribeNewHead sends the last recorded block header once.
+func (s *replaySession) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	header := &types.Header{
+		Number: new(big.Int).Set(s.lastHead),
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		select {
+		case ch <- header:
+		case <-quit:
+			return nil
+		}
+		<-quit
+		return nil
+	}), nil
+}
+
+// Close keeps the session for reconnects.
+func (s *replaySession) Close() {}
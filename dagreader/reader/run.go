@@ -0,0 +1,166 @@
+// Package reader reads DAG of an opera node and delivers its events parents first.
+//
+// Run reads from the config Source into the config Db (NewMemDb for no persistence)
+// and passes the same ordered events to the additional sinks:
+//
+//	cfg := reader.DefaultConfig()
+//	cfg.Source = reader.RPCSource("ws://127.0.0.1:4500")
+//	cfg.Db = reader.NewMemDb()
+//	res, err := reader.Run(ctx, cfg, reader.SinkFunc(func(e *reader.EventInfo) {
+//		fmt.Println(e.Block, e.Event.ID(), e.Role)
+//	}))
+//
+// Sink gets the events over a channel, SinkFunc gets them over a callback. Run returns
+// when ctx is cancelled or a finite source is read to the end, after the read events are written.
+// Db and sink errors are fatal: Run stops reading and returns the first of them.
+//
+// With Config.Epochs only the events of the epochs range are read, each epoch is recorded
+// as EpochInfo with its first and sealing blocks and validators.
+//
+// With Config.Verify the events are checked against their payloads, signatures and parents.
+// The failed ones are not dropped but written with EventInfo.Integrity, which lists the failed checks.
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+)
+
+// Config of the DAG reading.
+type Config struct {
+	// Source to read DAG from, see RPCSource, DatadirSource and ReplaySource
+	Source Source
+	// DagStart is the first block to read if Db has no checkpoint after it,
+	// AutoDagStart to detect it (the result is cached if Db is a DagStartState)
+	DagStart idx.Block
+	// Db stores the events and the checkpoint
+	Db Db
+	// RetryInterval of the not found events recovery, 0 to disable
+	RetryInterval time.Duration
+	// HeadsInterval of the DAG heads polling, 0 to disable
+	HeadsInterval time.Duration
+	// Concurrency is a number of parallel event requests
+	Concurrency int
+	// Buffer limits of the events which wait for their parents
+	Buffer BufferConfig
+	// Epochs to read, zero range reads all the blocks from DagStart or checkpoint.
+	// The range is read from the first block of its first epoch (the checkpoint is not moved)
+	// until its last epoch is sealed, with the events which are not confirmed by any block
+	// if they are read while the epoch is current. Epoch infos are stored if Db is an EpochStore.
+	Epochs EpochRange
+	// Verify fetches the event payloads and checks event hashes, payload hashes and signatures,
+	// Source must be a PayloadClient. It enables Buffer.Verify also.
+	// The failed events are written with EventInfo.Integrity.
+	Verify bool
+	// OnValidators is called with the validators of each read epoch before the epoch events
+	// are passed to the sinks, with nil if Source serves no validators (see ValidatorsClient)
+	OnValidators func(idx.Epoch, []*EpochValidator)
+}
+
+// AutoDagStart detects the first block with DAG, as the genesis blocks have no DAG.
+const AutoDagStart idx.Block = 0
+
+// DefaultConfig returns default config with no Source and Db.
+func DefaultConfig() Config {
+	return Config{
+		DagStart:      1,
+		RetryInterval: 10 * time.Minute,
+		Concurrency:   1,
+		Buffer:        DefaultBufferConfig(),
+	}
+}
+
+// Result of the finished Run.
+type Result struct {
+	// Dropped is a count of the events which still wait for their parents,
+	// they are read again on the next run
+	Dropped int
+	// Resume is the checkpoint block the next run starts from
+	Resume idx.Block
+}
+
+// Run reads DAG into cfg.Db and sinks until ctx is cancelled or the finite source is read to the end.
+func Run(ctx context.Context, cfg Config, sinks ...Sink) (*Result, error) {
+	if cfg.Source.Dial == nil {
+		return nil, errors.New("no DAG source")
+	}
+	if cfg.Db == nil {
+		return nil, errors.New("no db")
+	}
+
+	db := cfg.Db
+	if !cfg.Epochs.IsZero() {
+		db = rangeDb{db}
+	}
+	fanout, err := NewFanout(db, sinks...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Verify {
+		cfg.Buffer.Verify = true
+	}
+	buffer := NewEventsBuffer(fanout, cfg.Buffer)
+	reader := NewReader(cfg)
+
+	var gaps <-chan time.Time
+	if cfg.Buffer.GapTimeout > 0 {
+		ticker := time.NewTicker(cfg.Buffer.GapTimeout / 2)
+		defer ticker.Stop()
+		gaps = ticker.C
+	}
+
+loop:
+	for {
+		select {
+		case e, ok := <-reader.Events():
+			if !ok {
+				break loop
+			}
+			buffer.Push(e)
+		case <-gaps:
+			buffer.CheckGaps(reader.Request)
+		case <-buffer.Failed():
+			break loop
+		case <-fanout.Failed():
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	res, err := shutdown(reader, buffer, cfg.Db)
+	if errors.Is(reader.Err(), ErrNotVerifiable) {
+		return res, reader.Err()
+	}
+	for _, failed := range []error{reader.Err(), buffer.Err(), fanout.Err(), err} {
+		if failed != nil {
+			return res, fmt.Errorf("storage failure: %w", failed)
+		}
+	}
+	return res, nil
+}
+
+// shutdown stops reader and writes the events it has already read.
+func shutdown(reader *DagReader, buffer *EventsBuffer, db Storage) (*Result, error) {
+	reader.Log.Info("stop reading")
+	reader.Close()
+	for e := range reader.Events() {
+		buffer.Push(e)
+	}
+
+	buffer.Log.Info("flush buffered events")
+	dropped, err := buffer.Close()
+	if err != nil {
+		return &Result{Dropped: dropped}, err
+	}
+
+	resume, err := db.GetLastBlock()
+	return &Result{
+		Dropped: dropped,
+		Resume:  resume,
+	}, err
+}
@@ -0,0 +1,60 @@
+package reader
+
+import (
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// The storage types are shared with the bundled db packages.
+type (
+	// EventInfo is a read event with its block and consensus role.
+	EventInfo = internal.EventInfo
+	// Storage is what reader knows about the events read before.
+	Storage = internal.Storage
+	// Sink writes ordered events and calls EventInfo.Done when the event is written.
+	Sink = internal.Sink
+	// Db is a Storage and Sink with the checkpoint.
+	Db = internal.Db
+	// ValidatorStats is a per-epoch summary of the validator events.
+	ValidatorStats = internal.ValidatorStats
+	// Snapshot is the DAG as it is known when the block is decided.
+	Snapshot = internal.Snapshot
+	// MemDb is an in-memory Db.
+	MemDb = internal.MemDb
+	// DagStartState is a Db which caches the detected first block with DAG.
+	DagStartState = internal.DagStartState
+	// PruneState is a Db which remembers the cutoff of the pruned events.
+	PruneState = internal.PruneState
+	// PruneCutoff bounds the pruned events.
+	PruneCutoff = internal.PruneCutoff
+)
+
+const (
+	// PlaceholderMark ends role of the event which is detected but not found.
+	PlaceholderMark = internal.PlaceholderMark
+	// GapRole is a role of the missing event which is put in place of the never arrived one.
+	GapRole = internal.GapRole
+	// UnconfirmedBlock is a block of the events which are not confirmed by any block yet.
+	UnconfirmedBlock = internal.UnconfirmedBlock
+)
+
+// NewMemDb returns an empty in-memory Db.
+func NewMemDb() *MemDb {
+	return internal.NewMemDb()
+}
+
+// IsPlaceholder returns true if role means the event is detected but not found.
+func IsPlaceholder(role string) bool {
+	return internal.IsPlaceholder(role)
+}
+
+// SinkFunc is a Sink which calls the func for each ordered event.
+type SinkFunc func(*EventInfo)
+
+// Load implements Sink interface.
+func (f SinkFunc) Load(events <-chan *EventInfo) error {
+	for info := range events {
+		f(info)
+		info.Done()
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Fantom-foundation/go-opera/inter"
+	"github.com/Fantom-foundation/go-opera/inter/validatorpk"
+	"github.com/Fantom-foundation/lachesis-base/eventcheck/parentscheck"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The failed checks which EventInfo.Integrity lists.
+const (
+	// IntegrityID means the event hash doesn't match the requested ID,
+	// the event is stored under the requested ID
+	IntegrityID = "id"
+	// IntegrityPayload means the payload hash doesn't match the event one
+	IntegrityPayload = "payload"
+	// IntegrityCreator means the creator is not a validator of the epoch
+	IntegrityCreator = "creator"
+	// IntegritySig means the signature is not made by the creator key
+	IntegritySig = "sig"
+	// IntegrityParents means lamport, seq or epoch doesn't match the parents
+	IntegrityParents = "parents"
+	// IntegrityUnverified prefixes the check which is skipped as the source doesn't serve
+	// what it needs, e.g. "unverified=sig", such an event doesn't fail verification
+	IntegrityUnverified = "unverified="
+)
+
+// IntegrityFailed returns true if the listed checks have a failed one, not only skipped ones.
+func IntegrityFailed(integrity string) bool {
+	if integrity == "" {
+		return false
+	}
+	for _, check := range strings.Split(integrity, ",") {
+		if !strings.HasPrefix(check, IntegrityUnverified) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNotVerifiable is returned by Run if Config.Verify is set but the source is not a PayloadClient.
+var ErrNotVerifiable = errors.New("source doesn't serve event payloads to verify")
+
+// PayloadClient is a Client which serves what is needed to verify the events, see Config.Verify.
+type PayloadClient interface {
+	Client
+	GetEventPayload(ctx context.Context, h hash.Event) (*EventPayload, error)
+	// GetValidatorKeys returns public keys of the epoch validators
+	GetValidatorKeys(ctx context.Context, epoch idx.Epoch) (map[idx.ValidatorID]validatorpk.PubKey, error)
+}
+
+// EventPayload is the event with as much of its payload as the source serves.
+type EventPayload struct {
+	Event inter.EventI
+	// Payload is nil if it is not served completely, so payload hash is not verified
+	Payload inter.EventPayloadI
+	// Sig is nil if it is not served, so signature is not verified
+	Sig *inter.Signature
+}
+
+// verifier checks the fetched events, it caches validator keys of the recent epochs.
+type verifier struct {
+	keys map[idx.Epoch]map[idx.ValidatorID]validatorpk.PubKey
+	sync.Mutex
+}
+
+func newVerifier() *verifier {
+	return &verifier{
+		keys: make(map[idx.Epoch]map[idx.ValidatorID]validatorpk.PubKey),
+	}
+}
+
+// GetEvent gets the event with its payload and returns it with the failed checks.
+func (v *verifier) GetEvent(ctx context.Context, client PayloadClient, h hash.Event) (dag.Event, string, error) {
+	p, err := client.GetEventPayload(ctx, h)
+	if err != nil {
+		return nil, "", err
+	}
+	keys, err := v.validatorKeys(ctx, client, h.Epoch())
+	if err != nil {
+		return nil, "", err
+	}
+
+	failed := verifyPayload(h, p, keys)
+	var event dag.Event = p.Event
+	if p.Event.ID() != h {
+		event = withID(p.Event, h)
+	}
+	return event, strings.Join(failed, ","), nil
+}
+
+func (v *verifier) validatorKeys(ctx context.Context, client PayloadClient, epoch idx.Epoch) (map[idx.ValidatorID]validatorpk.PubKey, error) {
+	v.Lock()
+	defer v.Unlock()
+
+	if keys, ok := v.keys[epoch]; ok {
+		return keys, nil
+	}
+	keys, err := client.GetValidatorKeys(ctx, epoch)
+	if err != nil {
+		return nil, fmt.Errorf("validators of epoch %d: %w", epoch, err)
+	}
+	v.keys[epoch] = keys
+	delete(v.keys, epoch-2)
+	return keys, nil
+}
+
+// verifyPayload returns the failed and the skipped checks of the event with its payload.
+func verifyPayload(id hash.Event, p *EventPayload, keys map[idx.ValidatorID]validatorpk.PubKey) (failed []string) {
+	e := p.Event
+	// the event hash is calculated from the fields when it is built
+	if e.ID() != id {
+		failed = append(failed, IntegrityID)
+	}
+	if p.Payload == nil {
+		failed = append(failed, IntegrityUnverified+IntegrityPayload)
+	} else if inter.CalcPayloadHash(p.Payload) != e.PayloadHash() {
+		failed = append(failed, IntegrityPayload)
+	}
+
+	pubkey, ok := keys[e.Creator()]
+	if !ok {
+		return append(failed, IntegrityCreator)
+	}
+	if p.Sig == nil || pubkey.Type != validatorpk.Types.Secp256k1 {
+		failed = append(failed, IntegrityUnverified+IntegritySig)
+	} else if !crypto.VerifySignature(pubkey.Raw, e.HashToSign().Bytes(), p.Sig.Bytes()) {
+		failed = append(failed, IntegritySig)
+	}
+	return failed
+}
+
+// verifyParents returns the failed check of the event against its parents.
+// Placeholders and the events stored before schema version 2 have no creator or seq,
+// so only lamport and epoch are checked with them.
+func verifyParents(e dag.Event, parents dag.Events) (failed []string) {
+	complete := true
+	for _, p := range parents {
+		if p.Epoch() != e.Epoch() {
+			return []string{IntegrityParents}
+		}
+		if p.Creator() == 0 || p.Seq() == 0 {
+			complete = false
+		}
+	}
+
+	if complete {
+		if parentscheck.New().Validate(e, parents) != nil {
+			return []string{IntegrityParents}
+		}
+		return nil
+	}
+
+	var maxLamport idx.Lamport
+	for _, p := range parents {
+		maxLamport = idx.MaxLamport(maxLamport, p.Lamport())
+	}
+	if e.Lamport() != maxLamport+1 {
+		return []string{IntegrityParents}
+	}
+	return nil
+}
+
+// withID returns copy of the event with the ID.
+func withID(e dag.Event, id hash.Event) dag.Event {
+	m := dag.MutableBaseEvent{}
+	m.SetEpoch(id.Epoch())
+	m.SetLamport(id.Lamport())
+	m.SetSeq(e.Seq())
+	m.SetFrame(e.Frame())
+	m.SetCreator(e.Creator())
+	m.SetParents(e.Parents())
+
+	var idTail [24]byte
+	copy(idTail[:], id[8:])
+	return m.Build(idTail)
+}
+
+// joinIntegrity adds the failed checks to the listed ones.
+func joinIntegrity(listed string, failed []string) string {
+	if listed != "" {
+		failed = append([]string{listed}, failed...)
+	}
+	return strings.Join(failed, ",")
+}
@@ -0,0 +1,139 @@
+package reader
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/inter"
+	"github.com/Fantom-foundation/go-opera/inter/validatorpk"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag"
+	"github.com/Fantom-foundation/lachesis-base/inter/dag/tdag"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+func TestVerifyPayload(t *testing.T) {
+	require := require.New(t)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(err)
+	keys := map[idx.ValidatorID]validatorpk.PubKey{
+		1: {Type: validatorpk.Types.Secp256k1, Raw: crypto.FromECDSAPub(&key.PublicKey)},
+	}
+
+	txs := types.Transactions{
+		types.NewTransaction(1, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil),
+	}
+	me := &inter.MutableEventPayload{}
+	me.SetVersion(1)
+	me.SetEpoch(2)
+	me.SetLamport(1)
+	me.SetSeq(1)
+	me.SetCreator(1)
+	me.SetTxs(txs)
+	me.SetPayloadHash(inter.CalcPayloadHash(me))
+	unsigned := me.Build()
+	sig, err := crypto.Sign(unsigned.HashToSign().Bytes(), key)
+	require.NoError(err)
+	me.SetSig(inter.BytesToSignature(sig[:inter.SigSize]))
+	signed := me.Build()
+
+	verified := func(p *EventPayload) []string {
+		return verifyPayload(signed.ID(), p, keys)
+	}
+
+	s := signed.Sig()
+	require.Empty(verified(&EventPayload{Event: signed, Payload: signed, Sig: &s}))
+	// not served parts are listed as unverified, it is not a failure
+	unverified := verified(&EventPayload{Event: signed})
+	require.Equal([]string{"unverified=payload", "unverified=sig"}, unverified)
+	require.False(IntegrityFailed(strings.Join(unverified, ",")))
+
+	other := &inter.MutableEventPayload{}
+	other.SetVersion(1)
+	other.SetTxs(append(txs, txs[0]))
+	failed := verified(&EventPayload{Event: signed, Payload: other})
+	require.Equal([]string{IntegrityPayload, "unverified=sig"}, failed)
+	require.True(IntegrityFailed(strings.Join(failed, ",")))
+
+	wrong := inter.Signature{}
+	copy(wrong[:], s[:])
+	wrong[10] ^= 0xff
+	require.Equal([]string{"unverified=payload", IntegritySig}, verified(&EventPayload{Event: signed, Sig: &wrong}))
+
+	me.SetCreator(2)
+	require.Equal([]string{IntegrityID, "unverified=payload", IntegrityCreator}, verified(&EventPayload{Event: me.Build()}))
+}
+
+func TestVerifyParents(t *testing.T) {
+	require := require.New(t)
+
+	events := make(map[hash.Event]dag.Event)
+	var ordered dag.Events
+	tdag.ForEachRandEvent(tdag.GenNodes(3), 10, 2, rand.New(rand.NewSource(0)), tdag.ForEachEvent{
+		Process: func(e dag.Event, name string) {
+			events[e.ID()] = e
+			ordered = append(ordered, e)
+		},
+	})
+	parentsOf := func(e dag.Event) dag.Events {
+		parents := make(dag.Events, len(e.Parents()))
+		for i, p := range e.Parents() {
+			parents[i] = events[p]
+		}
+		return parents
+	}
+
+	for _, e := range ordered {
+		require.Empty(verifyParents(e, parentsOf(e)), e.ID().String())
+	}
+
+	var e dag.Event
+	for _, e = range ordered {
+		if e.SelfParent() != nil {
+			break
+		}
+	}
+	// seq is checked against the self-parent
+	wrong := &dag.MutableBaseEvent{}
+	wrong.SetEpoch(e.Epoch())
+	wrong.SetLamport(e.Lamport())
+	wrong.SetCreator(e.Creator())
+	wrong.SetSeq(e.Seq() + 1)
+	wrong.SetParents(e.Parents())
+	require.Equal([]string{IntegrityParents}, verifyParents(wrong.Build([24]byte{1}), parentsOf(e)))
+
+	// the unknown seq is not checked
+	parents := parentsOf(e)
+	parents[0] = NotFoundEvent(parents[0].ID())
+	require.Empty(verifyParents(wrong.Build([24]byte{1}), parents))
+
+	// lamport is checked always
+	wrong.SetLamport(e.Lamport() + 1)
+	require.Equal([]string{IntegrityParents}, verifyParents(wrong.Build([24]byte{1}), parents))
+}
+
+func TestVerifyUnsupported(t *testing.T) {
+	node := newFakeNode(1, 0)
+
+	cfg := DefaultConfig()
+	cfg.Source = Source{URL: "fake", Dial: node.dial, Finite: true}
+	cfg.Db = internal.NewMemDb()
+	cfg.Verify = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := Run(ctx, cfg)
+	require.ErrorIs(t, err, ErrNotVerifiable)
+	require.NoError(t, ctx.Err())
+}
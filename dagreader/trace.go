@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/inter"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// traceClient is a subset of the opera node API which trace-tx uses.
+type traceClient interface {
+	TransactionReceipt(ctx context.Context, tx common.Hash) (*types.Receipt, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	GetEventPayload(ctx context.Context, h hash.Event, inclTx bool) (inter.EventI, []common.Hash, error)
+}
+
+// TxTrace is the way of the transaction from the event which carries it to the block which executes it.
+type TxTrace struct {
+	Tx     common.Hash
+	Status uint64
+	Block  idx.Block
+	// Atropos of the block, its median time is the block time
+	Atropos inter.EventI
+	// Carrier is the first created event which carries the transaction, nil if it is not found
+	Carrier inter.EventI
+	// Carriers is a count of the events which carry the transaction
+	Carriers int
+	// Events is a count of the events from the carrier (excluding) to the atropos (including)
+	Events int
+	// Searched is a count of the events which were looked for the transaction
+	Searched int
+	// Stored is true if the searched events are the stored events of the block,
+	// otherwise they are the atropos ancestors within the lamport depth
+	Stored bool
+}
+
+// Latency is the time from the carrier creation to the block.
+func (t *TxTrace) Latency() time.Duration {
+	if t.Carrier == nil {
+		return 0
+	}
+	return t.Atropos.MedianTime().Time().Sub(t.Carrier.CreationTime().Time())
+}
+
+// TraceTx finds the first event which carries the transaction among the events of its block.
+// The block events are taken from db if they are stored (db may be nil),
+// otherwise the atropos ancestors down to depth lamports are fetched.
+func TraceTx(ctx context.Context, client traceClient, db internal.Storage, tx common.Hash, depth idx.Lamport) (*TxTrace, error) {
+	receipt, err := client.TransactionReceipt(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("receipt of %s: %w", tx.Hex(), err)
+	}
+	blk, err := client.BlockByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("block %s: %w", receipt.BlockNumber, err)
+	}
+
+	t := &TxTrace{
+		Tx:     tx,
+		Status: receipt.Status,
+		Block:  idx.Block(receipt.BlockNumber.Uint64()),
+	}
+	atropos := hash.Event(blk.Hash())
+
+	var stored []*internal.EventInfo
+	if db != nil {
+		stored, err = db.GetBlockEvents(t.Block)
+		if err != nil {
+			log.Warn("Block events are not read from db, fetch them", "err", err)
+			stored = nil
+		}
+	}
+	t.Stored = len(stored) > 0
+
+	var (
+		events = make(map[hash.Event]inter.EventI)
+		queue  = hash.Events{atropos}
+	)
+	for _, info := range stored {
+		if !internal.IsPlaceholder(info.Role) && info.Event.ID() != atropos {
+			queue = append(queue, info.Event.ID())
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if _, ok := events[id]; ok {
+			continue
+		}
+
+		e, txs, err := client.GetEventPayload(ctx, id, true)
+		if err != nil {
+			return nil, fmt.Errorf("event %s: %w", id.String(), err)
+		}
+		events[id] = e
+		for _, h := range txs {
+			if h != tx {
+				continue
+			}
+			t.Carriers++
+			if t.Carrier == nil || e.CreationTime() < t.Carrier.CreationTime() {
+				t.Carrier = e
+			}
+		}
+
+		if t.Stored {
+			continue
+		}
+		for _, p := range e.Parents() {
+			if p.Epoch() == atropos.Epoch() && p.Lamport()+depth >= atropos.Lamport() {
+				queue = append(queue, p)
+			}
+		}
+	}
+	t.Atropos = events[atropos]
+	t.Searched = len(events)
+
+	if t.Carrier != nil {
+		t.Events = countDescendants(events, t.Carrier.ID())
+	}
+	return t, nil
+}
+
+// countDescendants returns count of the events which are descendants of the event.
+func countDescendants(events map[hash.Event]inter.EventI, ancestor hash.Event) int {
+	children := make(map[hash.Event]hash.Events, len(events))
+	for id, e := range events {
+		for _, p := range e.Parents() {
+			children[p] = append(children[p], id)
+		}
+	}
+
+	var (
+		seen  = hash.EventsSet{}
+		queue = hash.Events{ancestor}
+	)
+	for len(queue) > 0 {
+		id := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for _, c := range children[id] {
+			if !seen.Contains(c) {
+				seen.Add(c)
+				queue = append(queue, c)
+			}
+		}
+	}
+	return len(seen)
+}
+
+// WriteTimeline writes the trace as a timeline, one step per line.
+func WriteTimeline(w io.Writer, t *TxTrace) error {
+	var (
+		start = t.Atropos.MedianTime().Time()
+		lines []string
+	)
+	step := func(at time.Time, format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf("%s\t%+.3fs\t", at.UTC().Format(time.RFC3339Nano), at.Sub(start).Seconds())+
+			fmt.Sprintf(format, args...))
+	}
+
+	if t.Carrier != nil {
+		start = t.Carrier.CreationTime().Time()
+		step(start, "event %s (lamport %d) created by validator %d carries tx %s, %d events carry it",
+			t.Carrier.ID().FullID(), t.Carrier.Lamport(), t.Carrier.Creator(), t.Tx.Hex(), t.Carriers)
+	}
+	step(t.Atropos.CreationTime().Time(), "atropos %s (lamport %d) created by validator %d",
+		t.Atropos.ID().FullID(), t.Atropos.Lamport(), t.Atropos.Creator())
+	step(t.Atropos.MedianTime().Time(), "block %d executes tx %s with status %d",
+		t.Block, t.Tx.Hex(), t.Status)
+
+	if t.Carrier != nil {
+		lines = append(lines, fmt.Sprintf("finality: %d events and %s after inclusion", t.Events, t.Latency()))
+	} else {
+		source := "atropos ancestors, increase --depth to search deeper"
+		if t.Stored {
+			source = "stored block events"
+		}
+		lines = append(lines, fmt.Sprintf("carrier event is not found among %d %s", t.Searched, source))
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
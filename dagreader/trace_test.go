@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-opera/inter"
+	"github.com/Fantom-foundation/lachesis-base/hash"
+	"github.com/Fantom-foundation/lachesis-base/inter/idx"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+)
+
+// traceNode serves one block of the events with transactions.
+type traceNode struct {
+	atropos hash.Event
+	events  map[hash.Event]inter.EventI
+	txs     map[hash.Event][]common.Hash
+}
+
+func (n *traceNode) TransactionReceipt(ctx context.Context, tx common.Hash) (*types.Receipt, error) {
+	return &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		BlockNumber: big.NewInt(7),
+	}, nil
+}
+
+func (n *traceNode) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	header := &types.Header{
+		Number: new(big.Int).Set(number),
+	}
+	header.SetExternalHash(common.Hash(n.atropos))
+	return types.NewBlockWithHeader(header), nil
+}
+
+func (n *traceNode) GetEventPayload(ctx context.Context, h hash.Event, inclTx bool) (inter.EventI, []common.Hash, error) {
+	e, ok := n.events[h]
+	if !ok {
+		return nil, nil, ethereum.NotFound
+	}
+	return e, n.txs[h], nil
+}
+
+func (n *traceNode) add(creator idx.ValidatorID, lamport idx.Lamport, created time.Duration, txs []common.Hash, parents ...inter.EventI) inter.EventI {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	e := &inter.MutableEventPayload{}
+	e.SetVersion(1)
+	e.SetEpoch(3)
+	e.SetSeq(1)
+	e.SetCreator(creator)
+	e.SetLamport(lamport)
+	e.SetCreationTime(inter.Timestamp(start.Add(created).UnixNano()))
+	e.SetMedianTime(inter.Timestamp(start.Add(created).UnixNano()))
+	var ids hash.Events
+	for _, p := range parents {
+		ids = append(ids, p.ID())
+	}
+	e.SetParents(ids)
+	e.SetPayloadHash(inter.EmptyPayloadHash(1))
+
+	built := &e.Build().Event
+	n.events[built.ID()] = built
+	n.txs[built.ID()] = txs
+	return built
+}
+
+func TestTraceTx(t *testing.T) {
+	require := require.New(t)
+
+	tx := common.Hash{1}
+	other := []common.Hash{{2}}
+	n := &traceNode{
+		events: make(map[hash.Event]inter.EventI),
+		txs:    make(map[hash.Event][]common.Hash),
+	}
+	old := n.add(4, 1, 0, other)
+	a := n.add(1, 2, time.Second, []common.Hash{tx}, old)
+	b := n.add(2, 3, 2*time.Second, other, a)
+	d := n.add(3, 2, 3*time.Second, []common.Hash{tx}, old)
+	c := n.add(1, 4, 4*time.Second, nil, a, b, d)
+	n.atropos = c.ID()
+
+	check := func(trace *TxTrace, searched int) {
+		require.Equal(idx.Block(7), trace.Block)
+		require.Equal(c.ID(), trace.Atropos.ID())
+		require.Equal(a.ID(), trace.Carrier.ID())
+		require.Equal(2, trace.Carriers)
+		require.Equal(2, trace.Events)
+		require.Equal(searched, trace.Searched)
+		require.Equal(3*time.Second, trace.Latency())
+	}
+
+	// old is out of depth
+	trace, err := TraceTx(context.Background(), n, nil, tx, 2)
+	require.NoError(err)
+	require.False(trace.Stored)
+	check(trace, 4)
+
+	db := internal.NewMemDb()
+	events := make(chan *internal.EventInfo, 4)
+	for _, e := range []inter.EventI{a, b, d, c} {
+		events <- &internal.EventInfo{Block: 7, Event: e}
+	}
+	close(events)
+	require.NoError(db.Load(events))
+
+	trace, err = TraceTx(context.Background(), n, db, tx, 0)
+	require.NoError(err)
+	require.True(trace.Stored)
+	check(trace, 4)
+
+	out := &bytes.Buffer{}
+	require.NoError(WriteTimeline(out, trace))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(lines, 4)
+	require.Contains(lines[0], "+0.000s\tevent "+a.ID().FullID())
+	require.Contains(lines[1], "+3.000s\tatropos "+c.ID().FullID())
+	require.Contains(lines[2], "block 7 executes")
+	require.Equal("finality: 2 events and 3s after inclusion", lines[3])
+
+	trace, err = TraceTx(context.Background(), n, nil, common.Hash{3}, 2)
+	require.NoError(err)
+	require.Nil(trace.Carrier)
+	out.Reset()
+	require.NoError(WriteTimeline(out, trace))
+	require.Contains(out.String(), "carrier event is not found among 4 atropos ancestors")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli"
+
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/api"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/internal"
+	"github.com/Fantom-foundation/lachesis-dag-tool/dagreader/reader"
+)
+
+var (
+	verifyFlag = cli.BoolFlag{
+		Name:  "verify",
+		Usage: "verify event hashes, payload hashes, signatures and parents, the failed events are flagged in db",
+	}
+
+	verifyReportFlag = cli.StringFlag{
+		Name:  "verify.report",
+		Usage: "file to append the events which fail verification to as newline delimited JSON",
+	}
+)
+
+// verifyReport is a sink which appends the events failed verification to the file.
+type verifyReport struct {
+	file *os.File
+}
+
+func newVerifyReport(path string) (*verifyReport, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifyReport{
+		file: file,
+	}, nil
+}
+
+// Load implements internal.Sink interface.
+// The failed events are rare, so each of them is written at once.
+func (r *verifyReport) Load(events <-chan *internal.EventInfo) error {
+	enc := json.NewEncoder(r.file)
+	for info := range events {
+		if reader.IntegrityFailed(info.Integrity) {
+			log.Warn("Event fails verification", "id", info.Event.ID(), "block", info.Block, "failed", info.Integrity)
+			if err := enc.Encode(api.NewEvent(info)); err != nil {
+				return err
+			}
+		}
+		info.Done()
+	}
+	return nil
+}
+
+func (r *verifyReport) Close() error {
+	return r.file.Close()
+}
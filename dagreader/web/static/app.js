@@ -0,0 +1,330 @@
+'use strict';
+
+// DAG visualizer: one lane per validator, events are placed by lamport time.
+(function () {
+  const SVG = 'http://www.w3.org/2000/svg';
+  const STEP_X = 24;
+  const STEP_Y = 36;
+  const MARGIN_X = 60;
+  const MARGIN_Y = 30;
+  const EPOCH_GAP = 2;
+  const RADIUS = 6;
+
+  const svg = document.getElementById('dag');
+  const details = document.getElementById('details');
+  const status = document.getElementById('status');
+
+  let dag = newDag();
+  let view = { x: 0, y: 0, w: 0, h: 0 };
+
+  function newDag() {
+    return {
+      events: new Map(),   // id -> event
+      children: new Map(), // id -> [id]
+      nodes: new Map(),    // id -> svg circle
+      edges: [],           // [{from, to, line}]
+      width: 0,
+      height: 0,
+    };
+  }
+
+  function isAtropos(e) {
+    return e.role.startsWith('atropos');
+  }
+
+  function isPlaceholder(e) {
+    return e.role.endsWith('*');
+  }
+
+  async function fetchJSON(url) {
+    const resp = await fetch(url);
+    const data = await resp.json();
+    if (!resp.ok) {
+      throw new Error(data.error || resp.statusText);
+    }
+    return data;
+  }
+
+  async function load(from, to) {
+    status.textContent = 'loading...';
+    const events = [];
+    for (let epoch = from; epoch <= to; epoch++) {
+      status.textContent = 'loading epoch ' + epoch + '...';
+      const list = await fetchJSON('api/epochs/' + epoch + '/events');
+      events.push(...(list || []));
+    }
+    status.textContent = events.length + ' events';
+    draw(events);
+  }
+
+  function layout(events) {
+    const creators = [...new Set(events.map(e => e.creator))].sort((a, b) => a - b);
+    const lanes = new Map(creators.map((c, i) => [c, i]));
+
+    const epochs = new Map();
+    for (const e of events) {
+      epochs.set(e.epoch, Math.max(epochs.get(e.epoch) || 0, e.lamport));
+    }
+    const offsets = new Map();
+    let offset = 0;
+    for (const epoch of [...epochs.keys()].sort((a, b) => a - b)) {
+      offsets.set(epoch, offset);
+      offset += epochs.get(epoch) + EPOCH_GAP;
+    }
+
+    // events of the same creator and lamport (forks) are shifted down
+    const taken = new Map();
+    for (const e of events) {
+      const key = e.creator + ':' + e.epoch + ':' + e.lamport;
+      const n = taken.get(key) || 0;
+      taken.set(key, n + 1);
+      e.x = MARGIN_X + (offsets.get(e.epoch) + e.lamport) * STEP_X;
+      e.y = MARGIN_Y + lanes.get(e.creator) * STEP_Y + n * RADIUS;
+    }
+
+    return {
+      creators: creators,
+      lanes: lanes,
+      offsets: offsets,
+      width: MARGIN_X * 2 + offset * STEP_X,
+      height: MARGIN_Y * 2 + creators.length * STEP_Y,
+    };
+  }
+
+  function el(name, attrs, parent) {
+    const node = document.createElementNS(SVG, name);
+    for (const [k, v] of Object.entries(attrs)) {
+      node.setAttribute(k, v);
+    }
+    if (parent) {
+      parent.appendChild(node);
+    }
+    return node;
+  }
+
+  function draw(events) {
+    svg.innerHTML = '';
+    dag = newDag();
+    details.innerHTML = '<p>Click an event to see its details.</p>';
+
+    const l = layout(events);
+    dag.width = l.width;
+    dag.height = l.height;
+
+    const lanes = el('g', {}, svg);
+    for (const [creator, i] of l.lanes) {
+      const y = MARGIN_Y + i * STEP_Y;
+      el('line', { class: 'lane', x1: MARGIN_X, y1: y, x2: l.width, y2: y }, lanes);
+      const label = el('text', { class: 'lane-label', x: 4, y: y + 4 }, lanes);
+      label.textContent = 'v' + creator;
+    }
+    for (const [epoch, offset] of l.offsets) {
+      const x = MARGIN_X + (offset - EPOCH_GAP / 2) * STEP_X;
+      el('line', { class: 'epoch', x1: x, y1: 0, x2: x, y2: l.height }, lanes);
+      const label = el('text', { class: 'lane-label', x: x + 4, y: 12 }, lanes);
+      label.textContent = 'epoch ' + epoch;
+    }
+
+    for (const e of events) {
+      dag.events.set(e.id, e);
+    }
+
+    const edges = el('g', {}, svg);
+    for (const e of events) {
+      for (const p of e.parents) {
+        if (!dag.children.has(p)) {
+          dag.children.set(p, []);
+        }
+        dag.children.get(p).push(e.id);
+
+        const parent = dag.events.get(p);
+        if (!parent) {
+          continue;
+        }
+        const line = el('line', { class: 'edge', x1: e.x, y1: e.y, x2: parent.x, y2: parent.y }, edges);
+        dag.edges.push({ from: e.id, to: p, line: line });
+      }
+    }
+
+    const nodes = el('g', {}, svg);
+    for (const e of events) {
+      let cls = 'node';
+      if (isAtropos(e)) {
+        cls += ' atropos';
+      }
+      if (isPlaceholder(e)) {
+        cls += ' placeholder';
+      }
+      const r = isAtropos(e) ? RADIUS * 1.4 : RADIUS;
+      const node = el('circle', { class: cls, cx: e.x, cy: e.y, r: r }, nodes);
+      const title = el('title', {}, node);
+      title.textContent = e.id + (e.role ? ' (' + e.role + ')' : '');
+      node.addEventListener('click', (ev) => {
+        ev.stopPropagation();
+        select(e.id);
+      });
+      dag.nodes.set(e.id, node);
+    }
+
+    resetView();
+  }
+
+  function walk(start, next) {
+    const seen = new Set();
+    const queue = [...next(start)];
+    while (queue.length > 0) {
+      const id = queue.pop();
+      if (seen.has(id)) {
+        continue;
+      }
+      seen.add(id);
+      queue.push(...next(id));
+    }
+    return seen;
+  }
+
+  function select(id) {
+    const ancestors = walk(id, x => (dag.events.get(x) || { parents: [] }).parents);
+    const descendants = walk(id, x => dag.children.get(x) || []);
+
+    for (const [nid, node] of dag.nodes) {
+      node.classList.toggle('selected', nid === id);
+      node.classList.toggle('ancestor', ancestors.has(nid));
+      node.classList.toggle('descendant', descendants.has(nid));
+    }
+    for (const edge of dag.edges) {
+      edge.line.classList.toggle('ancestor', edge.from === id || ancestors.has(edge.from));
+      edge.line.classList.toggle('descendant', descendants.has(edge.from) && (edge.to === id || descendants.has(edge.to)));
+    }
+
+    showDetails(id, ancestors.size, descendants.size);
+  }
+
+  async function showDetails(id, ancestors, descendants) {
+    details.innerHTML = '<p>loading...</p>';
+    let e;
+    try {
+      e = await fetchJSON('api/events/' + encodeURIComponent(id));
+    } catch (err) {
+      details.innerHTML = '';
+      details.appendChild(text('p', String(err)));
+      return;
+    }
+
+    details.innerHTML = '';
+    const dl = document.createElement('dl');
+    const row = (name, value) => {
+      dl.appendChild(text('dt', name));
+      const dd = document.createElement('dd');
+      if (value instanceof Node) {
+        dd.appendChild(value);
+      } else {
+        dd.textContent = value;
+      }
+      dl.appendChild(dd);
+    };
+    row('id', e.id);
+    row('epoch', e.epoch);
+    row('lamport', e.lamport);
+    row('creator', e.creator);
+    row('block', e.block);
+    row('role', e.role || '-');
+    row('parents', links(e.parents));
+    row('children', links(e.children || []));
+    row('ancestors (loaded)', ancestors);
+    row('descendants (loaded)', descendants);
+    details.appendChild(dl);
+  }
+
+  function text(tag, value) {
+    const node = document.createElement(tag);
+    node.textContent = value;
+    return node;
+  }
+
+  function links(ids) {
+    const list = document.createElement('div');
+    for (const id of ids) {
+      const a = text('a', id);
+      a.addEventListener('click', () => {
+        if (dag.events.has(id)) {
+          select(id);
+        } else {
+          showDetails(id, 0, 0);
+        }
+      });
+      list.appendChild(a);
+      list.appendChild(document.createElement('br'));
+    }
+    return list;
+  }
+
+  // pan & zoom
+
+  function applyView() {
+    svg.setAttribute('viewBox', [view.x, view.y, view.w, view.h].join(' '));
+  }
+
+  function resetView() {
+    const rect = svg.getBoundingClientRect();
+    view = { x: 0, y: 0, w: rect.width || dag.width, h: rect.height || dag.height };
+    applyView();
+  }
+
+  svg.addEventListener('wheel', (ev) => {
+    ev.preventDefault();
+    const rect = svg.getBoundingClientRect();
+    const k = ev.deltaY > 0 ? 1.2 : 1 / 1.2;
+    const px = view.x + (ev.clientX - rect.left) / rect.width * view.w;
+    const py = view.y + (ev.clientY - rect.top) / rect.height * view.h;
+    view.x = px - (px - view.x) * k;
+    view.y = py - (py - view.y) * k;
+    view.w *= k;
+    view.h *= k;
+    applyView();
+  }, { passive: false });
+
+  let drag = null;
+  svg.addEventListener('mousedown', (ev) => {
+    drag = { x: ev.clientX, y: ev.clientY };
+  });
+  window.addEventListener('mouseup', () => {
+    drag = null;
+  });
+  window.addEventListener('mousemove', (ev) => {
+    if (!drag) {
+      return;
+    }
+    const rect = svg.getBoundingClientRect();
+    view.x -= (ev.clientX - drag.x) / rect.width * view.w;
+    view.y -= (ev.clientY - drag.y) / rect.height * view.h;
+    drag = { x: ev.clientX, y: ev.clientY };
+    applyView();
+  });
+
+  document.getElementById('reset').addEventListener('click', resetView);
+
+  document.getElementById('range').addEventListener('submit', (ev) => {
+    ev.preventDefault();
+    const from = parseInt(document.getElementById('from').value, 10);
+    const to = parseInt(document.getElementById('to').value, 10);
+    if (to < from) {
+      status.textContent = 'invalid epoch range';
+      return;
+    }
+    load(from, to).catch(err => {
+      status.textContent = String(err);
+    });
+  });
+
+  // start from the epoch of the last stored block
+  fetchJSON('api/checkpoint')
+    .then(cp => fetchJSON('api/blocks/' + cp.block))
+    .then(events => {
+      if (events && events.length > 0) {
+        document.getElementById('from').value = events[0].epoch;
+        document.getElementById('to').value = events[0].epoch;
+      }
+    })
+    .catch(() => {});
+})();
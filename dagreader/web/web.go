@@ -0,0 +1,21 @@
+// Package web contains the DAG visualizer page served over the HTTP API.
+// All the assets are embedded into the binary, no external resources are required.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var assets embed.FS
+
+// Handler serves the visualizer static assets.
+func Handler() http.Handler {
+	static, err := fs.Sub(assets, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(static))
+}